@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/audit"
+	"github.com/your-username/singbox-xboard-client/internal/traffic"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "连接审计日志",
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "按条件导出审计日志（JSONL）",
+	Example: `  singbox-xboard audit export --since 7d
+  singbox-xboard audit export --since 2024-01-01 --until 2024-02-01 --host example.com -o jan.jsonl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := loadCoreConfig(cmd)
+
+		var filter audit.Filter
+		var err error
+		if value, _ := cmd.Flags().GetString("since"); value != "" {
+			if filter.Since, err = parseAuditTime(value); err != nil {
+				logrus.Fatalf("--since: %v", err)
+			}
+		}
+		if value, _ := cmd.Flags().GetString("until"); value != "" {
+			if filter.Until, err = parseAuditTime(value); err != nil {
+				logrus.Fatalf("--until: %v", err)
+			}
+		}
+		filter.Host, _ = cmd.Flags().GetString("host")
+		filter.Outbound, _ = cmd.Flags().GetString("outbound")
+
+		var out io.Writer = os.Stdout
+		if path, _ := cmd.Flags().GetString("output"); path != "" {
+			file, err := os.Create(path)
+			if err != nil {
+				logrus.Fatalf("创建输出文件失败: %v", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		w := bufio.NewWriter(out)
+		count, err := audit.Export(audit.Dir(cfg.Audit), filter, w)
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			logrus.Fatalf("导出审计日志失败: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "已导出 %d 条记录\n", count)
+	},
+}
+
+// parseAuditTime 解析时间，支持 RFC3339、本地日期（2024-01-02）和相对时长（24h、7d）
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	d, err := traffic.ParseRange(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("时间无效: %s", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func init() {
+	auditCmd.PersistentFlags().StringP("config", "c", "", "配置文件路径")
+
+	auditExportCmd.Flags().String("since", "", "起始时间，如 24h、7d、2024-01-02 或 RFC3339")
+	auditExportCmd.Flags().String("until", "", "截止时间，格式同 --since")
+	auditExportCmd.Flags().String("host", "", "只导出目标包含该关键字的连接")
+	auditExportCmd.Flags().String("outbound", "", "只导出使用该出站的连接")
+	auditExportCmd.Flags().StringP("output", "o", "", "输出文件，默认为标准输出")
+
+	auditCmd.AddCommand(auditExportCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/core"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+var coreCmd = &cobra.Command{
+	Use:   "core",
+	Short: "管理 sing-box 内核",
+}
+
+var coreListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出已安装的内核",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := loadCoreConfig(cmd)
+
+		installed, err := core.NewManager(cfg.Core).Installed()
+		if err != nil {
+			logrus.Fatalf("读取内核列表失败: %v", err)
+		}
+		if len(installed) == 0 {
+			fmt.Println("尚未安装任何内核")
+			return
+		}
+
+		for _, c := range installed {
+			mark := " "
+			if c.Version == cfg.Core.Version {
+				mark = "*"
+			}
+			status := "兼容"
+			if err := core.CheckCompatibility(c.Version); err != nil {
+				status = "不兼容"
+			}
+			fmt.Printf("%s %-16s %-6s %s\n", mark, c.Version, status, c.Path)
+		}
+	},
+}
+
+var coreInstallCmd = &cobra.Command{
+	Use:   "install [version]",
+	Short: "安装指定版本的内核",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+
+		if mirror, _ := cmd.Flags().GetString("mirror"); mirror != "" {
+			cfg.Core.Mirror = mirror
+		}
+		archive, _ := cmd.Flags().GetString("archive")
+		sum, _ := cmd.Flags().GetString("sha256")
+
+		binary, err := core.NewManager(cfg.Core).Install(args[0], core.InstallOptions{
+			Archive: archive,
+			SHA256:  sum,
+		})
+		if err != nil {
+			logrus.Fatalf("安装内核失败: %v", err)
+		}
+		fmt.Printf("已安装: %s\n", binary)
+
+		if use, _ := cmd.Flags().GetBool("use"); use {
+			pinCoreVersion(cfg, path, args[0])
+		}
+	},
+}
+
+var coreUseCmd = &cobra.Command{
+	Use:   "use [version]",
+	Short: "固定使用指定版本的内核",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+
+		if _, err := core.NewManager(cfg.Core).BinaryPath(args[0]); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		if err := core.CheckCompatibility(args[0]); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		pinCoreVersion(cfg, path, args[0])
+	},
+}
+
+var coreRemoveCmd = &cobra.Command{
+	Use:   "remove [version]",
+	Short: "删除指定版本的内核",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+
+		if err := core.NewManager(cfg.Core).Remove(args[0]); err != nil {
+			logrus.Fatalf("删除内核失败: %v", err)
+		}
+		fmt.Printf("已删除 sing-box %s\n", args[0])
+
+		// 删除的是固定版本时取消固定
+		if cfg.Core.Version == args[0] {
+			pinCoreVersion(cfg, path, "")
+		}
+	},
+}
+
+var coreCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "检查将要使用的内核及其兼容性",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := loadCoreConfig(cmd)
+
+		binary, err := singbox.NewManager(cfg).Binary()
+		if err != nil {
+			logrus.Fatalf("找不到 sing-box: %v", err)
+		}
+		version, err := core.ProbeVersion(binary)
+		if err != nil {
+			logrus.Fatalf("%v", err)
+		}
+
+		fmt.Printf("路径: %s\n", binary)
+		fmt.Printf("版本: %s\n", version)
+		fmt.Printf("支持范围: >= %s, < %s\n", core.MinSupportedVersion, core.MaxSupportedVersion)
+		if err := core.CheckCompatibility(version); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		fmt.Println("兼容")
+	},
+}
+
+func init() {
+	coreCmd.PersistentFlags().StringP("config", "c", "", "配置文件路径")
+
+	coreInstallCmd.Flags().String("archive", "", "本地发布包路径，不指定时从镜像下载")
+	coreInstallCmd.Flags().String("sha256", "", "发布包的 SHA-256")
+	coreInstallCmd.Flags().String("mirror", "", "下载地址模板，支持 {version} {file} {os} {arch}")
+	coreInstallCmd.Flags().Bool("use", false, "安装后固定使用该版本")
+
+	coreCmd.AddCommand(coreListCmd)
+	coreCmd.AddCommand(coreInstallCmd)
+	coreCmd.AddCommand(coreUseCmd)
+	coreCmd.AddCommand(coreRemoveCmd)
+	coreCmd.AddCommand(coreCheckCmd)
+	rootCmd.AddCommand(coreCmd)
+}
+
+// loadCoreConfig 加载配置，返回配置及其路径
+func loadCoreConfig(cmd *cobra.Command) (*config.Config, string) {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = config.GetDefaultConfigPath()
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		logrus.Fatalf("加载配置失败: %v", err)
+	}
+	return cfg, path
+}
+
+// pinCoreVersion 保存固定的内核版本，为空表示取消固定
+func pinCoreVersion(cfg *config.Config, path, version string) {
+	cfg.Core.Version = version
+	if err := config.Save(cfg, path); err != nil {
+		logrus.Fatalf("保存配置失败: %v", err)
+	}
+
+	if version == "" {
+		fmt.Println("已取消固定内核版本")
+	} else {
+		fmt.Printf("已固定使用 sing-box %s\n", version)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/clashapi"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+var modeCmd = &cobra.Command{
+	Use:   "mode [rule|global|direct]",
+	Short: "查看或切换规则模式",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+		client := modeClashClient(cfg)
+
+		if len(args) == 0 {
+			// 优先显示运行中实例的实际模式
+			if mode, err := client.GetMode(); err == nil {
+				fmt.Println(mode)
+				return
+			}
+			fmt.Println(singbox.NewManager(cfg).Mode())
+			return
+		}
+
+		mode := args[0]
+		if err := config.ValidateMode(mode); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+
+		// 更新应用配置和已生成的 sing-box 配置
+		cfg.Rules.Mode = mode
+		if err := singbox.NewManager(cfg).SetMode(cfg); err != nil {
+			logrus.Fatalf("切换规则模式失败: %v", err)
+		}
+		if err := config.Save(cfg, path); err != nil {
+			logrus.Fatalf("保存配置失败: %v", err)
+		}
+
+		// 通知运行中的实例
+		if err := client.SetMode(mode); err != nil {
+			fmt.Printf("规则模式已设置为 %s，将在下次启动 sing-box 时生效\n", mode)
+			return
+		}
+		fmt.Printf("规则模式已切换为 %s\n", mode)
+	},
+}
+
+// modeClashClient 按配置创建 Clash API 客户端
+func modeClashClient(cfg *config.Config) *clashapi.Client {
+	controller := cfg.Singbox.ClashAPI.ExternalController
+	if controller == "" {
+		controller = config.DefaultConfig().Singbox.ClashAPI.ExternalController
+	}
+	return clashapi.NewClient(controller, cfg.Singbox.ClashAPI.Secret)
+}
+
+func init() {
+	modeCmd.Flags().StringP("config", "c", "", "配置文件路径")
+	rootCmd.AddCommand(modeCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/ruleset"
+)
+
+var rulesetCmd = &cobra.Command{
+	Use:   "ruleset",
+	Short: "管理规则集",
+}
+
+var rulesetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出规则集及其缓存时间",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := loadCoreConfig(cmd)
+
+		infos, err := ruleset.NewManager(cfg.Singbox.Route).List()
+		if err != nil {
+			logrus.Fatalf("读取规则集失败: %v", err)
+		}
+
+		for _, info := range infos {
+			age := "未下载"
+			if info.Cached {
+				age = time.Duration(info.Age * float64(time.Second)).Round(time.Minute).String()
+			}
+			mark := " "
+			if info.Stale {
+				mark = "!"
+			}
+			fmt.Printf("%s %-28s %-6s %-10s %s\n", mark, info.Tag, info.Type, age, info.URL+info.Path)
+		}
+	},
+}
+
+var rulesetRefreshCmd = &cobra.Command{
+	Use:   "refresh [tag...]",
+	Short: "重新下载远程规则集，不指定标签时下载全部",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, _ := loadCoreConfig(cmd)
+
+		if err := ruleset.NewManager(cfg.Singbox.Route).Refresh(args...); err != nil {
+			logrus.Fatalf("%v", err)
+		}
+		fmt.Println("规则集已更新，重新生成配置后生效")
+	},
+}
+
+func init() {
+	rulesetCmd.PersistentFlags().StringP("config", "c", "", "配置文件路径")
+
+	rulesetCmd.AddCommand(rulesetListCmd)
+	rulesetCmd.AddCommand(rulesetRefreshCmd)
+	rootCmd.AddCommand(rulesetCmd)
+}
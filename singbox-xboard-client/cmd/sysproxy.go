@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/sysproxy"
+)
+
+var sysproxyCmd = &cobra.Command{
+	Use:       "sysproxy [on|off|restore]",
+	Short:     "查看、启用或禁用系统代理，restore 恢复异常退出后遗留的设置",
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off", "restore"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+
+		if len(args) == 0 {
+			if cfg.SystemProxy.Enabled {
+				fmt.Println("on")
+			} else {
+				fmt.Println("off")
+			}
+			return
+		}
+
+		switch args[0] {
+		case "on":
+			cfg.SystemProxy.Enabled = true
+		case "off":
+			cfg.SystemProxy.Enabled = false
+		case "restore":
+			if err := sysproxy.NewController(cfg.SystemProxy).Disable(); err != nil {
+				logrus.Fatalf("恢复系统代理设置失败: %v", err)
+			}
+			fmt.Println("已恢复")
+			return
+		default:
+			logrus.Fatalf("无效的参数: %s，可选 on、off 或 restore", args[0])
+		}
+
+		if err := config.Save(cfg, path); err != nil {
+			logrus.Fatalf("保存配置失败: %v", err)
+		}
+		fmt.Println("已保存，下次启动 sing-box 时生效")
+	},
+}
+
+func init() {
+	sysproxyCmd.Flags().StringP("config", "c", "", "配置文件路径")
+	rootCmd.AddCommand(sysproxyCmd)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+var tunCmd = &cobra.Command{
+	Use:       "tun [on|off]",
+	Short:     "查看、启用或禁用 TUN 入站",
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, path := loadCoreConfig(cmd)
+
+		if len(args) == 0 {
+			if cfg.TUNEnabled() {
+				fmt.Println("on")
+			} else {
+				fmt.Println("off")
+			}
+			return
+		}
+
+		switch args[0] {
+		case "on":
+			cfg.SetTUNEnabled(true)
+		case "off":
+			cfg.SetTUNEnabled(false)
+		default:
+			logrus.Fatalf("无效的参数: %s，可选 on 或 off", args[0])
+		}
+
+		if err := config.Save(cfg, path); err != nil {
+			logrus.Fatalf("保存配置失败: %v", err)
+		}
+		fmt.Println("已保存，重新生成配置并重启 sing-box 后生效")
+	},
+}
+
+func init() {
+	tunCmd.Flags().StringP("config", "c", "", "配置文件路径")
+	rootCmd.AddCommand(tunCmd)
+}
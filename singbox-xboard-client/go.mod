@@ -13,4 +13,6 @@ require (
 	github.com/go-resty/resty/v2 v2.11.0
 	github.com/tidwall/gjson v1.17.0
 	github.com/robfig/cron/v3 v3.0.1
+	go.etcd.io/bbolt v1.3.10
+	github.com/prometheus/client_golang v1.19.0
 )
\ No newline at end of file
@@ -0,0 +1,256 @@
+// Package alert 检查流量用量和订阅到期时间，越过阈值时发出提醒
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// 提醒类型
+const (
+	KindTraffic = "traffic" // 流量用量
+	KindExpiry  = "expiry"  // 订阅到期
+)
+
+// 提醒级别
+const (
+	LevelWarning  = "warning"
+	LevelCritical = "critical" // 已越过最严格的阈值
+)
+
+// 未配置时使用的默认阈值
+var (
+	defaultTrafficPercent = []int{80, 95}
+	defaultExpiryDays     = []int{7, 1}
+)
+
+// Alert 一条提醒
+type Alert struct {
+	Kind      string    `json:"kind"`
+	Level     string    `json:"level"`
+	Threshold int       `json:"threshold"` // 流量为百分比，到期为天数
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Watcher 提醒检查器，同一阈值只提醒一次，条件解除（流量重置、续费）后重新生效
+type Watcher struct {
+	mu        sync.Mutex
+	cfg       config.AlertConfig
+	statePath string
+	fired     map[string]bool // 已提醒的阈值
+	hooks     []func(Alert)
+	logger    *logrus.Logger
+}
+
+// NewWatcher 创建提醒检查器，已提醒的阈值保存在配置目录下的 alerts.json
+func NewWatcher(cfg config.AlertConfig) *Watcher {
+	w := &Watcher{
+		cfg:       cfg,
+		statePath: filepath.Join(config.GetConfigDir(), "alerts.json"),
+		fired:     make(map[string]bool),
+		logger:    logrus.New(),
+	}
+	if data, err := os.ReadFile(w.statePath); err == nil {
+		if err := json.Unmarshal(data, &w.fired); err != nil {
+			w.logger.Warnf("解析提醒记录失败: %v", err)
+		}
+	}
+	return w
+}
+
+// SetLogger 设置日志记录器
+func (w *Watcher) SetLogger(logger *logrus.Logger) {
+	w.logger = logger
+}
+
+// SetConfig 更新提醒配置
+func (w *Watcher) SetConfig(cfg config.AlertConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cfg = cfg
+}
+
+// OnAlert 注册提醒钩子
+func (w *Watcher) OnAlert(hook func(Alert)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Check 根据用户信息检查阈值，返回新产生的提醒并通知钩子。
+// 同时越过多个阈值时只提醒最严格的一个
+func (w *Watcher) Check(info *xboard.UserInfo) []Alert {
+	w.mu.Lock()
+	if !w.cfg.Enabled || info == nil {
+		w.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	changed := false
+	var alerts []Alert
+
+	// 流量，总量为 0 表示不限流量
+	if info.Total > 0 {
+		used := info.Upload + info.Download
+		percent := float64(used) * 100 / float64(info.Total)
+		thresholds := sortedThresholds(w.cfg.TrafficPercent, defaultTrafficPercent, false)
+
+		hit := 0
+		for _, threshold := range thresholds {
+			if c := w.arm(fmt.Sprintf("%s:%d", KindTraffic, threshold), percent >= float64(threshold)); c {
+				changed = true
+				if percent >= float64(threshold) {
+					hit = threshold
+				}
+			}
+		}
+		if hit > 0 {
+			alerts = append(alerts, Alert{
+				Kind:      KindTraffic,
+				Level:     level(hit == thresholds[len(thresholds)-1]),
+				Threshold: hit,
+				Title:     "流量提醒",
+				Message:   fmt.Sprintf("已使用 %.0f%% 的流量（%s / %s）", percent, formatBytes(used), formatBytes(info.Total)),
+				Time:      now,
+			})
+		}
+	}
+
+	// 到期时间，为空表示永不过期
+	if !info.ExpireTime.IsZero() {
+		remaining := info.ExpireTime.Sub(now)
+		thresholds := sortedThresholds(w.cfg.ExpiryDays, defaultExpiryDays, true)
+
+		hit := 0
+		for _, days := range thresholds {
+			crossed := remaining <= time.Duration(days)*24*time.Hour
+			if w.arm(fmt.Sprintf("%s:%d", KindExpiry, days), crossed) {
+				changed = true
+				if crossed {
+					hit = days
+				}
+			}
+		}
+		if hit > 0 {
+			message := fmt.Sprintf("订阅将于 %s 到期，剩余 %d 天", info.ExpireTime.Format("2006-01-02 15:04"), int(math.Ceil(remaining.Hours()/24)))
+			if remaining <= 0 {
+				message = fmt.Sprintf("订阅已于 %s 到期", info.ExpireTime.Format("2006-01-02 15:04"))
+			}
+			alerts = append(alerts, Alert{
+				Kind:      KindExpiry,
+				Level:     level(hit == thresholds[len(thresholds)-1] || remaining <= 0),
+				Threshold: hit,
+				Title:     "到期提醒",
+				Message:   message,
+				Time:      now,
+			})
+		}
+	}
+
+	if changed {
+		if err := w.save(); err != nil {
+			w.logger.Warnf("保存提醒记录失败: %v", err)
+		}
+	}
+	hooks := make([]func(Alert), len(w.hooks))
+	copy(hooks, w.hooks)
+	w.mu.Unlock()
+
+	for _, alert := range alerts {
+		w.logger.Warnf("%s: %s", alert.Title, alert.Message)
+		for _, hook := range hooks {
+			hook(alert)
+		}
+	}
+	return alerts
+}
+
+// arm 根据条件更新阈值状态：新越过时标记为已提醒，条件解除时清除。返回状态是否变化，调用方需持有 w.mu
+func (w *Watcher) arm(key string, crossed bool) bool {
+	if crossed == w.fired[key] {
+		return false
+	}
+	if crossed {
+		w.fired[key] = true
+	} else {
+		delete(w.fired, key)
+	}
+	return true
+}
+
+// save 原子保存已提醒的阈值，避免写入中断后重复提醒，调用方需持有 w.mu
+func (w *Watcher) save() error {
+	data, err := json.MarshalIndent(w.fired, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.statePath), 0755); err != nil {
+		return err
+	}
+
+	tmp := w.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.statePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// sortedThresholds 去掉无效值后排序，越靠后越严格：流量从小到大，天数从大到小
+func sortedThresholds(values, defaults []int, descending bool) []int {
+	if len(values) == 0 {
+		values = defaults
+	}
+
+	result := make([]int, 0, len(values))
+	for _, v := range values {
+		if v > 0 {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if descending {
+			return result[i] > result[j]
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+func level(critical bool) string {
+	if critical {
+		return LevelCritical
+	}
+	return LevelWarning
+}
+
+// formatBytes 格式化字节数
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
@@ -0,0 +1,118 @@
+package alert
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+const gb = int64(1) << 30
+
+func newTestWatcher(t *testing.T, cfg config.AlertConfig) *Watcher {
+	t.Helper()
+	w := NewWatcher(cfg)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	w.SetLogger(logger)
+	return w
+}
+
+func TestTrafficDedup(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	w := newTestWatcher(t, config.AlertConfig{Enabled: true})
+
+	var notified []Alert
+	w.OnAlert(func(a Alert) { notified = append(notified, a) })
+
+	steps := []struct {
+		used      int64 // 已用流量（GB），总量 100GB
+		threshold int   // 期望的提醒阈值，0 表示不提醒
+		level     string
+	}{
+		{50, 0, ""},
+		{81, 80, LevelWarning},
+		{85, 0, ""}, // 同一阈值只提醒一次
+		{99, 95, LevelCritical},
+		{99, 0, ""},
+		{10, 0, ""}, // 流量重置后阈值重新生效
+		{96, 95, LevelCritical},
+	}
+	for i, step := range steps {
+		alerts := w.Check(&xboard.UserInfo{Download: step.used * gb, Total: 100 * gb})
+		if step.threshold == 0 {
+			if len(alerts) != 0 {
+				t.Errorf("第 %d 步不应提醒，实际为 %+v", i+1, alerts)
+			}
+			continue
+		}
+		if len(alerts) != 1 || alerts[0].Threshold != step.threshold || alerts[0].Level != step.level {
+			t.Errorf("第 %d 步提醒为 %+v，期望阈值 %d、级别 %s", i+1, alerts, step.threshold, step.level)
+		}
+	}
+	if len(notified) != 3 {
+		t.Errorf("钩子收到 %d 条提醒，期望 3", len(notified))
+	}
+}
+
+func TestExpiryDedup(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	w := newTestWatcher(t, config.AlertConfig{Enabled: true, ExpiryDays: []int{3, 10, 0}})
+	now := time.Now()
+
+	steps := []struct {
+		remaining time.Duration
+		threshold int
+		level     string
+	}{
+		{30 * 24 * time.Hour, 0, ""},
+		{2 * 24 * time.Hour, 3, LevelCritical}, // 同时越过两个阈值时只提醒最严格的
+		{24 * time.Hour, 0, ""},
+		{60 * 24 * time.Hour, 0, ""}, // 续费
+		{9 * 24 * time.Hour, 10, LevelWarning},
+	}
+	for i, step := range steps {
+		alerts := w.Check(&xboard.UserInfo{ExpireTime: now.Add(step.remaining)})
+		if step.threshold == 0 {
+			if len(alerts) != 0 {
+				t.Errorf("第 %d 步不应提醒，实际为 %+v", i+1, alerts)
+			}
+			continue
+		}
+		if len(alerts) != 1 || alerts[0].Threshold != step.threshold || alerts[0].Level != step.level {
+			t.Errorf("第 %d 步提醒为 %+v，期望阈值 %d、级别 %s", i+1, alerts, step.threshold, step.level)
+		}
+	}
+}
+
+func TestDedupPersisted(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	info := &xboard.UserInfo{Download: 90 * gb, Total: 100 * gb}
+
+	if alerts := newTestWatcher(t, config.AlertConfig{Enabled: true}).Check(info); len(alerts) != 1 {
+		t.Fatalf("首次检查提醒为 %+v，期望 1 条", alerts)
+	}
+
+	// 重启后读取已提醒的阈值，不重复提醒
+	if alerts := newTestWatcher(t, config.AlertConfig{Enabled: true}).Check(info); len(alerts) != 0 {
+		t.Errorf("重启后提醒为 %+v，期望不提醒", alerts)
+	}
+}
+
+func TestDisabledOrUnlimited(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	disabled := newTestWatcher(t, config.AlertConfig{})
+	if alerts := disabled.Check(&xboard.UserInfo{Download: 100 * gb, Total: 100 * gb}); len(alerts) != 0 {
+		t.Errorf("未启用时提醒为 %+v", alerts)
+	}
+
+	// 总量为 0 且没有到期时间表示不限
+	enabled := newTestWatcher(t, config.AlertConfig{Enabled: true})
+	if alerts := enabled.Check(&xboard.UserInfo{Download: 100 * gb}); len(alerts) != 0 {
+		t.Errorf("不限流量时提醒为 %+v", alerts)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package alert
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// DesktopNotify 通过 notify-send 发送桌面通知
+func DesktopNotify(alert Alert) error {
+	urgency := "normal"
+	if alert.Level == LevelCritical {
+		urgency = "critical"
+	}
+
+	output, err := exec.Command("notify-send", "-a", "singbox-xboard", "-u", urgency, alert.Title, alert.Message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("发送桌面通知失败: %v: %s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package alert
+
+// DesktopNotify 目前只支持 Linux 桌面通知，其他平台由界面展示提醒
+func DesktopNotify(alert Alert) error {
+	return nil
+}
@@ -0,0 +1,292 @@
+// Package audit 将已关闭的连接写入按天和大小轮转的 JSONL 审计日志
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+// 未配置时的默认值
+const (
+	defaultMaxSize = 100 // MB
+	defaultMaxAge  = 90  // 天
+)
+
+// dayLayout 文件名中的日期格式
+const dayLayout = "2006-01-02"
+
+// fileName 日志文件名，如 connections-2024-01-02.jsonl、connections-2024-01-02.1.jsonl
+var fileName = regexp.MustCompile(`^connections-(\d{4}-\d{2}-\d{2})(?:\.(\d+))?\.jsonl$`)
+
+// Record 一条已关闭连接的记录
+type Record struct {
+	Time        time.Time `json:"time"` // 关闭时间
+	Start       time.Time `json:"start"`
+	Duration    float64   `json:"duration"` // 持续时长（秒）
+	Network     string    `json:"network"`
+	Inbound     string    `json:"inbound"`
+	Source      string    `json:"source"`
+	Host        string    `json:"host,omitempty"`
+	Destination string    `json:"destination"`
+	Outbound    string    `json:"outbound"`
+	Chains      []string  `json:"chains,omitempty"`
+	Rule        string    `json:"rule"`
+	ProcessPath string    `json:"process_path,omitempty"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+}
+
+// NewRecord 根据连接生成记录
+func NewRecord(conn singbox.Connection, closed time.Time) Record {
+	rule := conn.Rule
+	if conn.RulePayload != "" {
+		rule += "(" + conn.RulePayload + ")"
+	}
+
+	record := Record{
+		Time:        closed,
+		Start:       conn.Start,
+		Network:     conn.Network,
+		Inbound:     conn.Inbound,
+		Source:      conn.Source,
+		Host:        conn.Host,
+		Destination: conn.Destination,
+		Outbound:    conn.Outbound,
+		Chains:      conn.Chains,
+		Rule:        rule,
+		ProcessPath: conn.ProcessPath,
+		Upload:      conn.Upload,
+		Download:    conn.Download,
+	}
+	if !conn.Start.IsZero() {
+		record.Duration = closed.Sub(conn.Start).Round(time.Millisecond).Seconds()
+	}
+	return record
+}
+
+// Dir 返回审计日志目录
+func Dir(cfg config.AuditConfig) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	return filepath.Join(config.GetConfigDir(), "audit")
+}
+
+// Logger 审计日志写入器，跨天或文件超过大小上限时换新文件，并删除超过保留天数的文件
+type Logger struct {
+	dir     string
+	maxSize int64
+	maxAge  int
+	logger  *logrus.Logger
+
+	mu     sync.Mutex
+	file   *os.File
+	day    string
+	index  int
+	size   int64
+	closed bool
+}
+
+// Open 创建审计日志目录并清理过期文件，首次写入时才打开文件
+func Open(cfg config.AuditConfig) (*Logger, error) {
+	l := &Logger{
+		dir:     Dir(cfg),
+		maxSize: int64(cfg.MaxSize) << 20,
+		maxAge:  cfg.MaxAge,
+		logger:  logrus.New(),
+	}
+	if l.maxSize <= 0 {
+		l.maxSize = defaultMaxSize << 20
+	}
+	if l.maxAge <= 0 {
+		l.maxAge = defaultMaxAge
+	}
+
+	if err := os.MkdirAll(l.dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %w", err)
+	}
+	if err := l.prune(time.Now()); err != nil {
+		l.logger.Warnf("清理审计日志失败: %v", err)
+	}
+	return l, nil
+}
+
+// SetLogger 设置日志记录器
+func (l *Logger) SetLogger(logger *logrus.Logger) {
+	l.logger = logger
+}
+
+// Record 记录一次连接变化中已关闭的连接，某条写入失败时继续写入其余连接。
+// 连接来自 Clash API 的每秒快照，在两次快照之间建立并关闭的连接不会被记录
+func (l *Logger) Record(update singbox.ConnectionsUpdate) {
+	if len(update.Closed) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, conn := range update.Closed {
+		if err := l.Write(NewRecord(conn, now)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		l.logger.Warnf("写入审计日志失败 %d/%d 条: %v", len(errs), len(update.Closed), errors.Join(errs...))
+	}
+}
+
+// Write 写入一条记录
+func (l *Logger) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return fmt.Errorf("审计日志已关闭")
+	}
+	if err := l.rotate(record.Time, int64(len(line))); err != nil {
+		return err
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close 关闭当前文件，之后的写入均失败
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// rotate 确保当前文件属于 now 所在的日期且写入后不超过大小上限，调用方需持有 l.mu
+func (l *Logger) rotate(now time.Time, pending int64) error {
+	day := now.Format(dayLayout)
+	if l.file != nil && l.day == day && (l.size == 0 || l.size+pending <= l.maxSize) {
+		return nil
+	}
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	index := 0
+	if l.day == day {
+		index = l.index + 1
+	} else {
+		// 跨天时顺便清理过期文件
+		if err := l.prune(now); err != nil {
+			l.logger.Warnf("清理审计日志失败: %v", err)
+		}
+	}
+
+	// 重启后接着写当天未写满的文件
+	for {
+		info, err := os.Stat(l.path(day, index))
+		if err != nil || info.Size()+pending <= l.maxSize {
+			break
+		}
+		index++
+	}
+
+	file, err := os.OpenFile(l.path(day, index), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.day = day
+	l.index = index
+	l.size = info.Size()
+	return nil
+}
+
+// path 某天第 index 个文件的路径
+func (l *Logger) path(day string, index int) string {
+	if index == 0 {
+		return filepath.Join(l.dir, "connections-"+day+".jsonl")
+	}
+	return filepath.Join(l.dir, fmt.Sprintf("connections-%s.%d.jsonl", day, index))
+}
+
+// prune 删除超过保留天数的文件
+func (l *Logger) prune(now time.Time) error {
+	files, err := listFiles(l.dir)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.AddDate(0, 0, -l.maxAge).Format(dayLayout)
+	for _, f := range files {
+		if f.day < cutoff {
+			if err := os.Remove(f.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// logFile 目录中的一个日志文件
+type logFile struct {
+	path  string
+	day   string
+	index int
+}
+
+// listFiles 列出目录中的日志文件，按日期和序号排列
+func listFiles(dir string) ([]logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []logFile
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil || entry.IsDir() {
+			continue
+		}
+		index := 0
+		if match[2] != "" {
+			index, _ = strconv.Atoi(match[2])
+		}
+		files = append(files, logFile{
+			path:  filepath.Join(dir, entry.Name()),
+			day:   match[1],
+			index: index,
+		})
+	}
+
+	sortFiles(files)
+	return files, nil
+}
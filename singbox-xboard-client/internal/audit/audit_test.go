@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+func openTestLogger(t *testing.T, cfg config.AuditConfig) *Logger {
+	t.Helper()
+	cfg.Dir = t.TempDir()
+	l, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	l.SetLogger(logger)
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// readRecords 读取目录中所有文件的记录
+func readRecords(t *testing.T, dir string) []Record {
+	t.Helper()
+	files, err := listFiles(dir)
+	if err != nil {
+		t.Fatalf("listFiles 失败: %v", err)
+	}
+
+	var records []Record
+	for _, f := range files {
+		file, err := os.Open(f.path)
+		if err != nil {
+			t.Fatalf("打开 %s 失败: %v", f.path, err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("解析记录失败: %v", err)
+			}
+			records = append(records, record)
+		}
+		file.Close()
+	}
+	return records
+}
+
+func TestRecordContinuesAfterError(t *testing.T) {
+	l := openTestLogger(t, config.AuditConfig{})
+	start := time.Now().Add(-time.Second)
+
+	l.Record(singbox.ConnectionsUpdate{
+		Added: []singbox.Connection{{ID: "open", Host: "open.example.com"}}, // 仍在进行的连接不记录
+		Closed: []singbox.Connection{
+			{ID: "a", Host: "a.example.com", Start: start, Upload: 1},
+			{ID: "b", Host: "b.example.com", Start: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}, // 无法序列化
+			{ID: "c", Host: "c.example.com", Start: start, Download: 2, Rule: "domain", RulePayload: "c.example.com"},
+		},
+	})
+
+	records := readRecords(t, l.dir)
+	if len(records) != 2 || records[0].Host != "a.example.com" || records[1].Host != "c.example.com" {
+		t.Fatalf("记录为 %+v，期望跳过写入失败的 b 后继续写入 c", records)
+	}
+	if records[1].Rule != "domain(c.example.com)" || records[1].Duration < 1 {
+		t.Errorf("记录 c 为 %+v", records[1])
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	l := openTestLogger(t, config.AuditConfig{})
+	l.maxSize = 1 // 每条记录都超过上限，各写一个文件
+
+	for i := 0; i < 3; i++ {
+		if err := l.Write(Record{Time: time.Now(), Host: "example.com"}); err != nil {
+			t.Fatalf("Write 失败: %v", err)
+		}
+	}
+
+	files, err := listFiles(l.dir)
+	if err != nil {
+		t.Fatalf("listFiles 失败: %v", err)
+	}
+	if len(files) != 3 {
+		t.Errorf("生成了 %d 个文件，期望 3", len(files))
+	}
+	if len(readRecords(t, l.dir)) != 3 {
+		t.Error("轮转后记录数不为 3")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(dir, "connections-"+now.AddDate(0, 0, -10).Format(dayLayout)+".jsonl")
+	recent := filepath.Join(dir, "connections-"+now.AddDate(0, 0, -2).Format(dayLayout)+".1.jsonl")
+	other := filepath.Join(dir, "notes.txt")
+	for _, path := range []string{old, recent, other} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := Open(config.AuditConfig{Dir: dir, MaxAge: 7})
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	defer l.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("超过保留天数的文件应当删除")
+	}
+	for _, path := range []string{recent, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s 不应删除: %v", filepath.Base(path), err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxLineSize 单条记录的长度上限
+const maxLineSize = 1 << 20
+
+// Filter 导出条件，零值表示不限
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	Host     string // 目标域名或地址包含的关键字，不区分大小写
+	Outbound string // 实际使用的出站
+}
+
+// Match 记录是否满足条件
+func (f Filter) Match(record Record) bool {
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !record.Time.Before(f.Until) {
+		return false
+	}
+	if f.Outbound != "" && record.Outbound != f.Outbound {
+		return false
+	}
+	if f.Host != "" {
+		keyword := strings.ToLower(f.Host)
+		if !strings.Contains(strings.ToLower(record.Host), keyword) &&
+			!strings.Contains(strings.ToLower(record.Destination), keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// Export 按时间顺序将目录中满足条件的记录逐行写入 w，返回导出的条数
+func Export(dir string, filter Filter, w io.Writer) (int, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	// 文件按本地日期命名，先按日期跳过范围外的文件
+	var since, until string
+	if !filter.Since.IsZero() {
+		since = filter.Since.Local().Format(dayLayout)
+	}
+	if !filter.Until.IsZero() {
+		until = filter.Until.Local().Format(dayLayout)
+	}
+
+	count := 0
+	for _, f := range files {
+		if (since != "" && f.day < since) || (until != "" && f.day > until) {
+			continue
+		}
+		n, err := exportFile(f.path, filter, w)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// exportFile 导出单个文件中满足条件的记录，跳过无法解析的行
+func exportFile(path string, filter Filter, w io.Writer) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	count := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil || !filter.Match(record) {
+			continue
+		}
+		if _, err := w.Write(line); err != nil {
+			return count, err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// sortFiles 按日期和序号排列
+func sortFiles(files []logFile) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].day != files[j].day {
+			return files[i].day < files[j].day
+		}
+		return files[i].index < files[j].index
+	})
+}
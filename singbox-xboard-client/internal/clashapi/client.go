@@ -0,0 +1,148 @@
+// Package clashapi 封装 sing-box 实验性 Clash API 的访问
+package clashapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Client Clash API 客户端
+type Client struct {
+	baseURL    string
+	httpClient *resty.Client
+}
+
+// NewClient 创建 Clash API 客户端，controller 为 external_controller 地址
+func NewClient(controller, secret string) *Client {
+	baseURL := strings.TrimRight(controller, "/")
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "http://" + baseURL
+	}
+
+	httpClient := resty.New().
+		SetTimeout(5 * time.Second).
+		SetBaseURL(baseURL)
+	if secret != "" {
+		httpClient.SetAuthToken(secret)
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}
+}
+
+// GetConnections 获取当前所有连接
+func (c *Client) GetConnections() (*Snapshot, error) {
+	resp, err := c.httpClient.R().
+		SetResult(&Snapshot{}).
+		Get("/connections")
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*Snapshot), nil
+}
+
+// CloseConnection 关闭指定连接
+func (c *Client) CloseConnection(id string) error {
+	resp, err := c.httpClient.R().
+		Delete("/connections/" + url.PathEscape(id))
+	return checkResponse(resp, err)
+}
+
+// CloseAllConnections 关闭所有连接
+func (c *Client) CloseAllConnections() error {
+	resp, err := c.httpClient.R().
+		Delete("/connections")
+	return checkResponse(resp, err)
+}
+
+// GetMode 获取当前规则模式
+func (c *Client) GetMode() (string, error) {
+	resp, err := c.httpClient.R().
+		SetResult(&Configs{}).
+		Get("/configs")
+	if err := checkResponse(resp, err); err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(resp.Result().(*Configs).Mode), nil
+}
+
+// SetMode 切换规则模式，对新建连接立即生效
+func (c *Client) SetMode(mode string) error {
+	resp, err := c.httpClient.R().
+		SetBody(Configs{Mode: mode}).
+		Patch("/configs")
+	return checkResponse(resp, err)
+}
+
+// GetProxies 获取所有出站和出站组，包含 urltest 的测速记录
+func (c *Client) GetProxies() (map[string]Proxy, error) {
+	resp, err := c.httpClient.R().
+		SetResult(&Proxies{}).
+		Get("/proxies")
+	if err := checkResponse(resp, err); err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*Proxies).Proxies, nil
+}
+
+// SelectProxy 在 selector 出站组中选择出站
+func (c *Client) SelectProxy(group, name string) error {
+	resp, err := c.httpClient.R().
+		SetBody(map[string]string{"name": name}).
+		Put("/proxies/" + url.PathEscape(group))
+	return checkResponse(resp, err)
+}
+
+// GetDelay 通过指定出站访问 testURL 测试延迟（毫秒），超时或失败时返回错误
+func (c *Client) GetDelay(name, testURL string, timeout time.Duration) (int, error) {
+	var result struct {
+		Delay int `json:"delay"`
+	}
+	resp, err := c.httpClient.R().
+		SetQueryParams(map[string]string{
+			"url":     testURL,
+			"timeout": strconv.FormatInt(timeout.Milliseconds(), 10),
+		}).
+		SetResult(&result).
+		Get("/proxies/" + url.PathEscape(name) + "/delay")
+	if err := checkResponse(resp, err); err != nil {
+		return 0, err
+	}
+
+	return result.Delay, nil
+}
+
+// StatusError Clash API 返回的错误状态码。测速时 504 表示超时，503 表示无法连接
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Clash API 返回错误: %s", e.Status)
+}
+
+// checkResponse 检查请求错误和状态码
+func checkResponse(resp *resty.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("请求 Clash API 失败: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	default:
+		return &StatusError{Code: resp.StatusCode(), Status: resp.Status()}
+	}
+}
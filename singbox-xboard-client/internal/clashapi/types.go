@@ -0,0 +1,69 @@
+package clashapi
+
+import "time"
+
+// Snapshot GET /connections 的响应
+type Snapshot struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+	Memory        uint64       `json:"memory"`
+}
+
+// Connection 单条连接
+type Connection struct {
+	ID          string    `json:"id"`
+	Metadata    Metadata  `json:"metadata"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+	Chains      []string  `json:"chains"` // 出站链，第一个为实际使用的出站
+	Rule        string    `json:"rule"`
+	RulePayload string    `json:"rulePayload"`
+}
+
+// Metadata 连接元数据
+type Metadata struct {
+	Network         string `json:"network"`
+	Type            string `json:"type"` // 入站，如 mixed/mixed-in
+	SourceIP        string `json:"sourceIP"`
+	DestinationIP   string `json:"destinationIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+	DNSMode         string `json:"dnsMode"`
+	ProcessPath     string `json:"processPath"`
+}
+
+// Configs GET/PATCH /configs 的请求和响应，这里只关心规则模式
+type Configs struct {
+	Mode string `json:"mode"`
+}
+
+// Proxies GET /proxies 的响应
+type Proxies struct {
+	Proxies map[string]Proxy `json:"proxies"`
+}
+
+// Proxy 出站或出站组
+type Proxy struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Now     string         `json:"now,omitempty"` // 出站组当前选中的出站
+	All     []string       `json:"all,omitempty"` // 出站组的成员
+	History []DelayHistory `json:"history"`
+}
+
+// LastDelay 最近一次测速的延迟（毫秒），没有记录或测速失败时为 0
+func (p Proxy) LastDelay() int {
+	if len(p.History) == 0 {
+		return 0
+	}
+	return p.History[len(p.History)-1].Delay
+}
+
+// DelayHistory 一次测速结果，Delay 为 0 表示失败
+type DelayHistory struct {
+	Time  time.Time `json:"time"`
+	Delay int       `json:"delay"`
+}
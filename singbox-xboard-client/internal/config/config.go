@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
 
@@ -25,6 +26,30 @@ type Config struct {
 	
 	// 规则配置
 	Rules RulesConfig `json:"rules" yaml:"rules"`
+	
+	// 内核配置
+	Core CoreConfig `json:"core" yaml:"core"`
+
+	// 系统代理配置
+	SystemProxy SystemProxyConfig `json:"system_proxy" yaml:"system_proxy"`
+
+	// 流量和到期提醒
+	Alerts AlertConfig `json:"alerts" yaml:"alerts"`
+
+	// Prometheus 指标
+	Metrics MetricsConfig `json:"metrics" yaml:"metrics"`
+
+	// 连接审计日志
+	Audit AuditConfig `json:"audit" yaml:"audit"`
+
+	// 定时策略
+	Policies []PolicyConfig `json:"policies,omitempty" yaml:"policies,omitempty"`
+
+	// 节点健康检查
+	Health HealthConfig `json:"health" yaml:"health"`
+
+	// 智能选择节点
+	Smart SmartConfig `json:"smart" yaml:"smart"`
 }
 
 // SubscriptionConfig 订阅配置
@@ -33,6 +58,9 @@ type SubscriptionConfig struct {
 	Token          string `json:"token" yaml:"token"`                     // 认证令牌
 	UpdateInterval int    `json:"update_interval" yaml:"update_interval"` // 更新间隔（分钟）
 	AutoUpdate     bool   `json:"auto_update" yaml:"auto_update"`         // 自动更新
+
+	ReportTraffic  bool `json:"report_traffic" yaml:"report_traffic"`                     // 定期向面板上报各节点流量
+	ReportInterval int  `json:"report_interval,omitempty" yaml:"report_interval,omitempty"` // 上报间隔（分钟），默认 5
 }
 
 // SingboxConfig sing-box 相关配置
@@ -41,58 +69,265 @@ type SingboxConfig struct {
 	Outbounds []OutboundConfig `json:"outbounds" yaml:"outbounds"` // 出站配置
 	DNS       DNSConfig        `json:"dns" yaml:"dns"`             // DNS 配置
 	Route     RouteConfig      `json:"route" yaml:"route"`         // 路由配置
+
+	Supervisor SupervisorConfig `json:"supervisor" yaml:"supervisor"` // 进程守护配置
+	ClashAPI   ClashAPIConfig   `json:"clash_api" yaml:"clash_api"`   // Clash API 配置
+}
+
+// ClashAPIConfig sing-box Clash API 配置，用于查询和管理运行中的实例
+type ClashAPIConfig struct {
+	ExternalController string `json:"external_controller" yaml:"external_controller"` // 监听地址
+	Secret             string `json:"secret" yaml:"secret"`                           // 访问密钥
+}
+
+// SupervisorConfig sing-box 进程守护配置
+type SupervisorConfig struct {
+	RestartPolicy      string `json:"restart_policy" yaml:"restart_policy"`             // 重启策略：never, on-failure, always
+	InitialBackoff     int    `json:"initial_backoff" yaml:"initial_backoff"`           // 首次重启等待（秒）
+	MaxBackoff         int    `json:"max_backoff" yaml:"max_backoff"`                   // 最大重启等待（秒）
+	CrashLoopThreshold int    `json:"crash_loop_threshold" yaml:"crash_loop_threshold"` // 连续快速失败多少次后放弃
+	CrashLoopWindow    int    `json:"crash_loop_window" yaml:"crash_loop_window"`       // 运行不足该时长即视为快速失败（秒）
 }
 
 // InboundConfig 入站配置
 type InboundConfig struct {
-	Type       string `json:"type" yaml:"type"`               // 类型：mixed, tun
+	Type       string `json:"type" yaml:"type"`               // 类型：mixed, socks, http, redirect, tproxy, tun
 	Tag        string `json:"tag" yaml:"tag"`                 // 标签
 	Listen     string `json:"listen" yaml:"listen"`           // 监听地址
 	ListenPort int    `json:"listen_port" yaml:"listen_port"` // 监听端口
+	Disabled   bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"` // 禁用后不生成该入站
+
+	AllowLAN bool          `json:"allow_lan,omitempty" yaml:"allow_lan,omitempty"` // 允许局域网连接，监听回环地址时改为监听全部地址
+	Users    []InboundUser `json:"users,omitempty" yaml:"users,omitempty"`         // 认证用户，仅 mixed、socks、http 使用，为空时不认证
+	Network  string        `json:"network,omitempty" yaml:"network,omitempty"`     // tproxy 监听的网络：tcp, udp，为空时两者都监听
+
+	Sniff                    *bool  `json:"sniff,omitempty" yaml:"sniff,omitempty"`                                           // 嗅探协议和域名，默认开启
+	SniffOverrideDestination *bool  `json:"sniff_override_destination,omitempty" yaml:"sniff_override_destination,omitempty"` // 用嗅探到的域名替换目标地址，tun 默认开启，其余默认关闭
+	SniffTimeout             string `json:"sniff_timeout,omitempty" yaml:"sniff_timeout,omitempty"`                           // 嗅探超时，如 300ms
+	Outbound                 string `json:"outbound,omitempty" yaml:"outbound,omitempty"`                                     // 该入站的流量固定使用的出站，为空时按路由规则分流
+
+	TUN *TUNConfig `json:"tun,omitempty" yaml:"tun,omitempty"` // TUN 选项，仅 tun 类型使用
+}
+
+// InboundUser 入站认证用户
+type InboundUser struct {
+	Username string `json:"username" yaml:"username"` // 用户名
+	Password string `json:"password" yaml:"password"` // 密码
+}
+
+// RedactedPassword 接口返回配置时代替认证密码，原样提交时保留原密码
+const RedactedPassword = "********"
+
+// HasAuth 是否设置了认证用户且每个用户都有密码
+func (in InboundConfig) HasAuth() bool {
+	if len(in.Users) == 0 {
+		return false
+	}
+	for _, user := range in.Users {
+		if user.Password == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Exposed 入站是否接受本机以外的连接：允许局域网连接，或显式监听了非回环地址
+func (in InboundConfig) Exposed() bool {
+	return in.AllowLAN || (in.Listen != "" && !IsLoopback(in.Listen))
+}
+
+// CheckAuth 对本机以外开放的 mixed、socks、http 入站必须为每个认证用户设置密码，避免成为开放代理。
+// redirect 和 tproxy 不支持认证，tun 不监听端口，均不检查
+func (in InboundConfig) CheckAuth() error {
+	switch in.Type {
+	case "mixed", "socks", "http":
+	default:
+		return nil
+	}
+	if in.Exposed() && !in.HasAuth() {
+		name := in.Tag
+		if name == "" {
+			name = in.Type
+		}
+		return fmt.Errorf("入站 %s 对本机以外开放，需要为每个认证用户设置密码", name)
+	}
+	return nil
+}
+
+// IsLoopback 监听地址是否只接受本机连接
+func IsLoopback(listen string) bool {
+	if listen == "localhost" {
+		return true
+	}
+	addr, err := netip.ParseAddr(listen)
+	return err == nil && addr.IsLoopback()
+}
+
+// RedactPasswords 返回隐藏了认证密码的入站副本
+func RedactPasswords(inbounds []InboundConfig) []InboundConfig {
+	result := make([]InboundConfig, len(inbounds))
+	for i, in := range inbounds {
+		result[i] = in
+		if len(in.Users) == 0 {
+			continue
+		}
+		result[i].Users = make([]InboundUser, len(in.Users))
+		for j, user := range in.Users {
+			if user.Password != "" {
+				user.Password = RedactedPassword
+			}
+			result[i].Users[j] = user
+		}
+	}
+	return result
+}
+
+// RestorePasswords 将提交的入站中仍为 RedactedPassword 的密码换回 previous 中
+// 同一入站同一用户的原密码，入站按标签匹配，未设置标签时按位置匹配
+func RestorePasswords(inbounds, previous []InboundConfig) error {
+	key := func(in InboundConfig, index int) string {
+		if in.Tag != "" {
+			return in.Tag
+		}
+		return fmt.Sprintf("#%d", index)
+	}
+	passwords := make(map[string]string)
+	for i, in := range previous {
+		for _, user := range in.Users {
+			passwords[key(in, i)+"\x00"+user.Username] = user.Password
+		}
+	}
+
+	for i := range inbounds {
+		in := &inbounds[i]
+		for j := range in.Users {
+			user := &in.Users[j]
+			if user.Password != RedactedPassword {
+				continue
+			}
+			password, ok := passwords[key(*in, i)+"\x00"+user.Username]
+			if !ok {
+				return fmt.Errorf("入站 %s 的用户 %s 需要重新设置密码", key(*in, i), user.Username)
+			}
+			user.Password = password
+		}
+	}
+	return nil
+}
+
+// TUNConfig TUN 入站选项，未设置的字段使用默认值
+type TUNConfig struct {
+	InterfaceName       string   `json:"interface_name,omitempty" yaml:"interface_name,omitempty"`               // 网卡名称
+	Stack               string   `json:"stack,omitempty" yaml:"stack,omitempty"`                                 // 协议栈：system, gvisor, mixed
+	MTU                 int      `json:"mtu,omitempty" yaml:"mtu,omitempty"`                                     // MTU
+	Inet4Address        string   `json:"inet4_address,omitempty" yaml:"inet4_address,omitempty"`                 // IPv4 地址，默认 172.19.0.1/30
+	Inet6Address        string   `json:"inet6_address,omitempty" yaml:"inet6_address,omitempty"`                 // IPv6 地址，为空时不启用 IPv6
+	RouteAddress        []string `json:"route_address,omitempty" yaml:"route_address,omitempty"`                 // 只路由这些网段
+	RouteExcludeAddress []string `json:"route_exclude_address,omitempty" yaml:"route_exclude_address,omitempty"` // 不路由这些网段
+	IncludeUID          []int    `json:"include_uid,omitempty" yaml:"include_uid,omitempty"`                     // 只代理这些用户，仅 Linux
+	ExcludeUID          []int    `json:"exclude_uid,omitempty" yaml:"exclude_uid,omitempty"`                     // 不代理这些用户，仅 Linux
+	StrictRoute         *bool    `json:"strict_route,omitempty" yaml:"strict_route,omitempty"`                   // 严格路由，默认开启
+	AutoRedirect        bool     `json:"auto_redirect,omitempty" yaml:"auto_redirect,omitempty"`                 // 使用 nftables 重定向，需要 Linux 和 sing-box 1.10+
 }
 
 // OutboundConfig 出站配置
 type OutboundConfig struct {
-	Type string `json:"type" yaml:"type"` // 类型：direct, block, selector, urltest
-	Tag  string `json:"tag" yaml:"tag"`   // 标签
+	Type      string   `json:"type" yaml:"type"`                               // 类型：direct, block, selector, urltest
+	Tag       string   `json:"tag" yaml:"tag"`                                 // 标签
+	Outbounds []string `json:"outbounds,omitempty" yaml:"outbounds,omitempty"` // 分组成员，为空时使用订阅节点分组
+	Default   string   `json:"default,omitempty" yaml:"default,omitempty"`     // selector 默认出站
 }
 
 // DNSConfig DNS 配置
 type DNSConfig struct {
-	Servers []DNSServer `json:"servers" yaml:"servers"` // DNS 服务器列表
-	Rules   []DNSRule   `json:"rules" yaml:"rules"`     // DNS 规则
+	Servers []DNSServer `json:"servers" yaml:"servers"`                 // DNS 服务器列表
+	Rules   []DNSRule   `json:"rules" yaml:"rules"`                     // DNS 规则
+	Final   string      `json:"final,omitempty" yaml:"final,omitempty"` // 默认服务器，为空时使用第一个
+
+	FakeIP FakeIPConfig `json:"fakeip" yaml:"fakeip"` // FakeIP 配置
+
+	Strategy     string `json:"strategy,omitempty" yaml:"strategy,omitempty"`           // 默认解析策略：prefer_ipv4, prefer_ipv6, ipv4_only, ipv6_only
+	DisableCache bool   `json:"disable_cache,omitempty" yaml:"disable_cache,omitempty"` // 禁用 DNS 缓存
+	ClientSubnet string `json:"client_subnet,omitempty" yaml:"client_subnet,omitempty"` // 默认附带的 EDNS 客户端子网
+}
+
+// FakeIPConfig FakeIP 配置，启用后匹配的域名返回虚假地址，连接时再还原为域名
+type FakeIPConfig struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled"`                                   // 是否启用
+	Inet4Range   string   `json:"inet4_range,omitempty" yaml:"inet4_range,omitempty"`       // IPv4 地址池，默认 198.18.0.0/15
+	Inet6Range   string   `json:"inet6_range,omitempty" yaml:"inet6_range,omitempty"`       // IPv6 地址池，默认 fc00::/18
+	Domain       []string `json:"domain,omitempty" yaml:"domain,omitempty"`                 // 使用 FakeIP 的域名，与后两项都为空时匹配全部域名
+	DomainSuffix []string `json:"domain_suffix,omitempty" yaml:"domain_suffix,omitempty"`   // 使用 FakeIP 的域名后缀
+	RuleSet      []string `json:"rule_set,omitempty" yaml:"rule_set,omitempty"`             // 使用 FakeIP 的规则集
+	Exclude      []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`               // 额外排除的域名后缀，局域网和联网检测域名已默认排除
 }
 
 // DNSServer DNS 服务器配置
 type DNSServer struct {
-	Tag     string `json:"tag" yaml:"tag"`         // 标签
-	Address string `json:"address" yaml:"address"` // 地址
-	Detour  string `json:"detour" yaml:"detour"`   // 出站标签
+	Tag     string `json:"tag" yaml:"tag"`                             // 标签
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`       // 类型：udp, tcp, tls, quic, https, h3, dhcp, system, hosts，为空时按地址前缀识别
+	Address string `json:"address,omitempty" yaml:"address,omitempty"` // 地址，可省略类型前缀；dhcp 类型填写网卡名，为空时自动选择
+	Detour  string `json:"detour,omitempty" yaml:"detour,omitempty"`   // 出站标签
+
+	AddressResolver string `json:"address_resolver,omitempty" yaml:"address_resolver,omitempty"` // 地址为域名时用于解析的服务器
+	Strategy        string `json:"strategy,omitempty" yaml:"strategy,omitempty"`                 // 解析策略：prefer_ipv4, prefer_ipv6, ipv4_only, ipv6_only
+	ClientSubnet    string `json:"client_subnet,omitempty" yaml:"client_subnet,omitempty"`       // 附带的 EDNS 客户端子网
+
+	Hosts     map[string][]string `json:"hosts,omitempty" yaml:"hosts,omitempty"`           // hosts 类型的静态记录
+	HostsPath []string            `json:"hosts_path,omitempty" yaml:"hosts_path,omitempty"` // hosts 类型读取的 hosts 文件
 }
 
-// DNSRule DNS 规则
+// DNSRule DNS 规则，多个匹配字段之间为或关系
 type DNSRule struct {
-	Domain []string `json:"domain,omitempty" yaml:"domain,omitempty"` // 域名列表
-	Server string   `json:"server" yaml:"server"`                     // DNS 服务器标签
+	Domain        []string `json:"domain,omitempty" yaml:"domain,omitempty"`                 // 域名列表
+	DomainSuffix  []string `json:"domain_suffix,omitempty" yaml:"domain_suffix,omitempty"`   // 域名后缀
+	DomainKeyword []string `json:"domain_keyword,omitempty" yaml:"domain_keyword,omitempty"` // 域名关键字
+	DomainRegex   []string `json:"domain_regex,omitempty" yaml:"domain_regex,omitempty"`     // 域名正则
+	RuleSet       []string `json:"rule_set,omitempty" yaml:"rule_set,omitempty"`             // 规则集
+	QueryType     []string `json:"query_type,omitempty" yaml:"query_type,omitempty"`         // 查询类型，如 A、AAAA、HTTPS
+	Server        string   `json:"server" yaml:"server"`                                     // DNS 服务器标签
+
+	DisableCache bool   `json:"disable_cache,omitempty" yaml:"disable_cache,omitempty"` // 不缓存匹配的查询
+	ClientSubnet string `json:"client_subnet,omitempty" yaml:"client_subnet,omitempty"` // 覆盖服务器的客户端子网
 }
 
 // RouteConfig 路由配置
 type RouteConfig struct {
 	Rules    []RouteRule `json:"rules" yaml:"rules"`       // 路由规则
 	RuleSet  []RuleSet   `json:"rule_set" yaml:"rule_set"` // 规则集
-	GeoIP    GeoIPConfig `json:"geoip" yaml:"geoip"`       // GeoIP 配置
-	GeoSite  GeoSiteConfig `json:"geosite" yaml:"geosite"`   // GeoSite 配置
+	Presets  RuleSetPresetConfig `json:"presets" yaml:"presets"` // 内置规则集
+	GeoIP    GeoIPConfig `json:"geoip" yaml:"geoip"`       // GeoIP 配置，已废弃，改用规则集
+	GeoSite  GeoSiteConfig `json:"geosite" yaml:"geosite"`   // GeoSite 配置，已废弃，改用规则集
+	Final    string      `json:"final,omitempty" yaml:"final,omitempty"` // 默认出站，为空时为 proxy
+
+	FindProcess bool `json:"find_process,omitempty" yaml:"find_process,omitempty"` // 查找连接所属进程，使用进程或用户规则时自动开启
 }
 
 // RouteRule 路由规则
 type RouteRule struct {
-	Type     string   `json:"type,omitempty" yaml:"type,omitempty"`         // 规则类型
-	Domain   []string `json:"domain,omitempty" yaml:"domain,omitempty"`     // 域名
-	IP       []string `json:"ip,omitempty" yaml:"ip,omitempty"`             // IP
-	Port     []int    `json:"port,omitempty" yaml:"port,omitempty"`         // 端口
-	Protocol []string `json:"protocol,omitempty" yaml:"protocol,omitempty"` // 协议
-	RuleSet  []string `json:"rule_set,omitempty" yaml:"rule_set,omitempty"` // 规则集
-	Outbound string   `json:"outbound" yaml:"outbound"`                     // 出站标签
+	Type   string      `json:"type,omitempty" yaml:"type,omitempty"`     // 规则类型：default（默认）, logical
+	Mode   string      `json:"mode,omitempty" yaml:"mode,omitempty"`     // 逻辑规则模式：and, or
+	Rules  []RouteRule `json:"rules,omitempty" yaml:"rules,omitempty"`   // 逻辑规则的子规则，子规则不设置出站
+	Invert bool        `json:"invert,omitempty" yaml:"invert,omitempty"` // 反选匹配结果
+
+	Domain        []string `json:"domain,omitempty" yaml:"domain,omitempty"`                 // 域名
+	DomainSuffix  []string `json:"domain_suffix,omitempty" yaml:"domain_suffix,omitempty"`   // 域名后缀
+	DomainKeyword []string `json:"domain_keyword,omitempty" yaml:"domain_keyword,omitempty"` // 域名关键字
+	DomainRegex   []string `json:"domain_regex,omitempty" yaml:"domain_regex,omitempty"`     // 域名正则
+	IP            []string `json:"ip,omitempty" yaml:"ip,omitempty"`                         // 目标 IP 或 CIDR
+	SourceIP      []string `json:"source_ip,omitempty" yaml:"source_ip,omitempty"`           // 来源 IP 或 CIDR
+	Port          []int    `json:"port,omitempty" yaml:"port,omitempty"`                     // 端口
+	PortRange     []string `json:"port_range,omitempty" yaml:"port_range,omitempty"`         // 端口范围，如 1000:2000、:3000、4000:
+	Network       []string `json:"network,omitempty" yaml:"network,omitempty"`               // 网络：tcp, udp
+	Protocol      []string `json:"protocol,omitempty" yaml:"protocol,omitempty"`             // 嗅探协议
+	Inbound       []string `json:"inbound,omitempty" yaml:"inbound,omitempty"`               // 入站标签
+	ProcessName   []string `json:"process_name,omitempty" yaml:"process_name,omitempty"`     // 进程名
+	ProcessPath   []string `json:"process_path,omitempty" yaml:"process_path,omitempty"`     // 进程路径
+	User          []string `json:"user,omitempty" yaml:"user,omitempty"`                     // 用户名，仅 Linux
+	UserID        []int    `json:"user_id,omitempty" yaml:"user_id,omitempty"`               // 用户 ID，仅 Linux
+	RuleSet       []string `json:"rule_set,omitempty" yaml:"rule_set,omitempty"`             // 规则集
+
+	Outbound string `json:"outbound,omitempty" yaml:"outbound,omitempty"` // 出站标签，逻辑规则的子规则为空
 }
 
 // RuleSet 规则集配置
@@ -102,6 +337,18 @@ type RuleSet struct {
 	Format string `json:"format" yaml:"format"` // 格式：binary, source
 	Path   string `json:"path,omitempty" yaml:"path,omitempty"` // 本地路径
 	URL    string `json:"url,omitempty" yaml:"url,omitempty"`   // 远程地址
+
+	DownloadDetour string `json:"download_detour,omitempty" yaml:"download_detour,omitempty"` // 下载使用的出站
+	UpdateInterval string `json:"update_interval,omitempty" yaml:"update_interval,omitempty"` // 更新间隔，如 1d、12h
+
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"` // 停用后不下载，引用它的路由和 DNS 规则一并跳过
+}
+
+// RuleSetPresetConfig 内置规则集（geosite-cn、geoip-cn 等）的下载配置
+type RuleSetPresetConfig struct {
+	Mirror         string `json:"mirror,omitempty" yaml:"mirror,omitempty"`                   // 下载地址模板，支持 {kind} {tag}
+	DownloadDetour string `json:"download_detour,omitempty" yaml:"download_detour,omitempty"` // 下载使用的出站
+	UpdateInterval string `json:"update_interval,omitempty" yaml:"update_interval,omitempty"` // 更新间隔，如 1d、12h
 }
 
 // GeoIPConfig GeoIP 配置
@@ -125,11 +372,172 @@ type UIConfig struct {
 	Secret string `json:"secret" yaml:"secret"` // API 密钥
 }
 
+// CoreConfig sing-box 内核管理配置
+type CoreConfig struct {
+	Version   string            `json:"version" yaml:"version"`                         // 固定使用的版本，为空时自动查找
+	Mirror    string            `json:"mirror" yaml:"mirror"`                           // 下载地址模板，支持 {version} {file} {os} {arch}
+	Dir       string            `json:"dir,omitempty" yaml:"dir,omitempty"`             // 安装目录，默认为配置目录下的 cores
+	Checksums map[string]string `json:"checksums,omitempty" yaml:"checksums,omitempty"` // 发布包文件名到 SHA-256 的映射
+}
+
+// SystemProxyConfig 系统代理配置，sing-box 启动时将桌面代理指向本地入站，停止后恢复原设置
+type SystemProxyConfig struct {
+	Enabled  bool     `json:"enabled" yaml:"enabled"`                       // 是否自动设置系统代理
+	Bypass   []string `json:"bypass,omitempty" yaml:"bypass,omitempty"`     // 不走代理的地址，为空时使用默认列表
+	Backends []string `json:"backends,omitempty" yaml:"backends,omitempty"` // 使用的设置方式：gnome, kde, env，为空时使用全部可用方式
+	EnvFile  string   `json:"env_file,omitempty" yaml:"env_file,omitempty"` // env 方式写入的环境变量文件，默认为 ~/.config/environment.d 下的文件
+}
+
+// AlertConfig 流量和到期提醒配置，每次获取用户信息后检查
+type AlertConfig struct {
+	Enabled        bool  `json:"enabled" yaml:"enabled"`                                   // 是否启用提醒
+	TrafficPercent []int `json:"traffic_percent,omitempty" yaml:"traffic_percent,omitempty"` // 已用流量达到总量的百分比时提醒，默认 80、95
+	ExpiryDays     []int `json:"expiry_days,omitempty" yaml:"expiry_days,omitempty"`         // 距到期不足天数时提醒，默认 7、1
+	DesktopNotify  bool  `json:"desktop_notify" yaml:"desktop_notify"`                     // 同时发送桌面通知
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"` // 是否提供 /metrics
+	Listen  string `json:"listen" yaml:"listen"`   // 单独的监听地址，如 0.0.0.0:9100，为空时由 Web UI 提供
+}
+
+// AuditConfig 连接审计日志配置，每条已关闭的连接写入一行 JSON
+type AuditConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`             // 是否记录
+	Dir     string `json:"dir,omitempty" yaml:"dir,omitempty"` // 日志目录，默认为配置目录下的 audit
+	MaxSize int    `json:"max_size" yaml:"max_size"`           // 单个文件的大小上限（MB），超过后轮转，默认 100
+	MaxAge  int    `json:"max_age" yaml:"max_age"`             // 保留天数，默认 90
+}
+
+// HealthConfig 节点健康检查配置，定期测试各节点延迟并保留最近 24 小时的记录
+type HealthConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`                     // 是否定期测试
+	Interval int    `json:"interval" yaml:"interval"`                   // 测试间隔（分钟），默认 5
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`         // 测试地址，默认与 urltest 相同
+	Timeout  int    `json:"timeout,omitempty" yaml:"timeout,omitempty"` // 超时（毫秒），默认 3000，最长 4000
+	MinScore int    `json:"min_score" yaml:"min_score"`                 // 可用性评分低于该值的节点不参与自动测速选择，0 表示不排除
+}
+
+// SmartConfig 智能选择节点配置，综合延迟、面板负载、在线状态和地区偏好定期为 select 组选择节点
+type SmartConfig struct {
+	Enabled  bool     `json:"enabled" yaml:"enabled"`                     // 是否自动切换 select 组的节点
+	Interval int      `json:"interval" yaml:"interval"`                   // 重新选择的间隔（分钟），默认 10
+	Regions  []string `json:"regions,omitempty" yaml:"regions,omitempty"` // 偏好地区，按节点位置或名称匹配，靠前的优先
+	Margin   int      `json:"margin" yaml:"margin"`                       // 新节点得分需高出当前节点的分数才切换，默认 10
+}
+
+// PolicyConfig 定时策略，到达 schedule 指定的时间时执行配置的动作
+type PolicyConfig struct {
+	Name     string `json:"name" yaml:"name"`                             // 名称，不能重复
+	Schedule string `json:"schedule" yaml:"schedule"`                     // cron 表达式，如 0 9 * * 1-5，支持 CRON_TZ= 前缀和 @daily 等写法
+	Disabled bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"` // 停用后不再定时执行，仍可手动触发
+
+	Mode            string   `json:"mode,omitempty" yaml:"mode,omitempty"`                           // 切换规则模式：rule, global, direct
+	Selector        string   `json:"selector,omitempty" yaml:"selector,omitempty"`                   // selector 出站组，默认 proxy
+	Outbound        string   `json:"outbound,omitempty" yaml:"outbound,omitempty"`                   // 在 selector 中选择的出站
+	EnableRuleSets  []string `json:"enable_rule_sets,omitempty" yaml:"enable_rule_sets,omitempty"`   // 启用的规则集
+	DisableRuleSets []string `json:"disable_rule_sets,omitempty" yaml:"disable_rule_sets,omitempty"` // 停用的规则集
+}
+
 // RulesConfig 规则配置
 type RulesConfig struct {
 	Mode string `json:"mode" yaml:"mode"` // 规则模式：rule, global, direct
 }
 
+// 规则模式
+const (
+	ModeRule   = "rule"   // 按路由规则分流
+	ModeGlobal = "global" // 全部流量走代理
+	ModeDirect = "direct" // 全部流量直连
+)
+
+// ValidateMode 检查规则模式是否有效
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeRule, ModeGlobal, ModeDirect:
+		return nil
+	default:
+		return fmt.Errorf("无效的规则模式: %s", mode)
+	}
+}
+
+// ValidateInbounds 检查启用的入站，对本机以外开放的代理入站必须认证
+func (c *Config) ValidateInbounds() error {
+	for _, in := range c.Singbox.Inbounds {
+		if in.Disabled {
+			continue
+		}
+		if err := in.CheckAuth(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TUNEnabled 是否启用了 TUN 入站
+func (c *Config) TUNEnabled() bool {
+	for _, in := range c.Singbox.Inbounds {
+		if in.Type == "tun" && !in.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTUNEnabled 启用或禁用 TUN 入站，保留已有的 TUN 选项；没有 TUN 入站时按默认选项添加
+func (c *Config) SetTUNEnabled(enabled bool) {
+	found := false
+	for i := range c.Singbox.Inbounds {
+		if c.Singbox.Inbounds[i].Type == "tun" {
+			c.Singbox.Inbounds[i].Disabled = !enabled
+			found = true
+		}
+	}
+
+	if !found && enabled {
+		c.Singbox.Inbounds = append(c.Singbox.Inbounds, InboundConfig{
+			Type: "tun",
+			Tag:  "tun-in",
+		})
+	}
+}
+
+// LANEnabled 是否允许局域网连接 mixed 入站
+func (c *Config) LANEnabled() bool {
+	for _, in := range c.Singbox.Inbounds {
+		if in.Type == "mixed" && !in.Disabled && in.AllowLAN {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLANEnabled 允许或禁止局域网连接 mixed 入站。
+// 允许时每个 mixed 入站都必须设置带密码的认证用户，否则不做修改并返回错误
+func (c *Config) SetLANEnabled(enabled bool) error {
+	found := false
+	for _, in := range c.Singbox.Inbounds {
+		if in.Type != "mixed" {
+			continue
+		}
+		found = true
+		if enabled && !in.HasAuth() {
+			return fmt.Errorf("入站 %s 未设置带密码的认证用户，不能允许局域网连接", in.Tag)
+		}
+	}
+	if !found && enabled {
+		return fmt.Errorf("没有设置认证用户的 mixed 入站，不能允许局域网连接")
+	}
+
+	for i := range c.Singbox.Inbounds {
+		if c.Singbox.Inbounds[i].Type == "mixed" {
+			c.Singbox.Inbounds[i].AllowLAN = enabled
+		}
+	}
+	return nil
+}
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
@@ -170,6 +578,22 @@ func DefaultConfig() *Config {
 					},
 				},
 			},
+			Supervisor: SupervisorConfig{
+				RestartPolicy:      "on-failure",
+				InitialBackoff:     1,
+				MaxBackoff:         60,
+				CrashLoopThreshold: 5,
+				CrashLoopWindow:    30,
+			},
+			Route: RouteConfig{
+				Presets: RuleSetPresetConfig{
+					DownloadDetour: "proxy",
+					UpdateInterval: "1d",
+				},
+			},
+			ClashAPI: ClashAPIConfig{
+				ExternalController: "127.0.0.1:9091",
+			},
 		},
 		UI: UIConfig{
 			Listen: "127.0.0.1",
@@ -178,6 +602,27 @@ func DefaultConfig() *Config {
 		Rules: RulesConfig{
 			Mode: "rule",
 		},
+		Alerts: AlertConfig{
+			Enabled:        true,
+			TrafficPercent: []int{80, 95},
+			ExpiryDays:     []int{7, 1},
+			DesktopNotify:  true,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Health: HealthConfig{
+			Enabled:  true,
+			Interval: 5,
+		},
+		Smart: SmartConfig{
+			Interval: 10,
+			Margin:   10,
+		},
+		Audit: AuditConfig{
+			MaxSize: 100,
+			MaxAge:  90,
+		},
 	}
 }
 
@@ -222,6 +667,9 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
+	if err := cfg.ValidateInbounds(); err != nil {
+		return nil, fmt.Errorf("配置无效: %w", err)
+	}
 
 	return cfg, nil
 }
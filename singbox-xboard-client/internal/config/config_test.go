@@ -0,0 +1,103 @@
+package config
+
+import "testing"
+
+func TestSetLANEnabled(t *testing.T) {
+	authed := InboundConfig{Type: "mixed", Tag: "mixed-in", Users: []InboundUser{{Username: "user", Password: "pass"}}}
+	noPassword := InboundConfig{Type: "mixed", Tag: "mixed-in", Users: []InboundUser{{Username: "user"}}}
+	socks := InboundConfig{Type: "socks", Tag: "socks-in"}
+
+	tests := []struct {
+		name     string
+		inbounds []InboundConfig
+		ok       bool
+	}{
+		{"已设置认证", []InboundConfig{authed, socks}, true},
+		{"没有认证用户", []InboundConfig{{Type: "mixed", Tag: "mixed-in"}}, false},
+		{"用户没有密码", []InboundConfig{noPassword}, false},
+		{"没有 mixed 入站", []InboundConfig{socks}, false},
+		{"没有入站", nil, false},
+	}
+	for _, tt := range tests {
+		var cfg Config
+		cfg.Singbox.Inbounds = append([]InboundConfig{}, tt.inbounds...)
+
+		err := cfg.SetLANEnabled(true)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: SetLANEnabled(true) = %v，期望成功为 %v", tt.name, err, tt.ok)
+		}
+		if cfg.LANEnabled() != tt.ok {
+			t.Errorf("%s: LANEnabled() = %v，期望 %v", tt.name, cfg.LANEnabled(), tt.ok)
+		}
+
+		// 禁止局域网连接总是可以
+		if err := cfg.SetLANEnabled(false); err != nil || cfg.LANEnabled() {
+			t.Errorf("%s: SetLANEnabled(false) = %v，LANEnabled() = %v", tt.name, err, cfg.LANEnabled())
+		}
+	}
+}
+
+func TestValidateInbounds(t *testing.T) {
+	users := []InboundUser{{Username: "user", Password: "pass"}}
+
+	tests := []struct {
+		name string
+		in   InboundConfig
+		ok   bool
+	}{
+		{"仅本机", InboundConfig{Type: "mixed"}, true},
+		{"监听 localhost", InboundConfig{Type: "mixed", Listen: "localhost"}, true},
+		{"监听全部地址未认证", InboundConfig{Type: "mixed", Listen: "0.0.0.0"}, false},
+		{"局域网未认证", InboundConfig{Type: "socks", AllowLAN: true}, false},
+		{"监听全部地址且已认证", InboundConfig{Type: "http", Listen: "0.0.0.0", Users: users}, true},
+		{"已禁用", InboundConfig{Type: "mixed", Listen: "0.0.0.0", Disabled: true}, true},
+		{"tproxy 不支持认证", InboundConfig{Type: "tproxy", Listen: "0.0.0.0"}, true},
+	}
+	for _, tt := range tests {
+		var cfg Config
+		cfg.Singbox.Inbounds = []InboundConfig{tt.in}
+		if err := cfg.ValidateInbounds(); (err == nil) != tt.ok {
+			t.Errorf("%s: ValidateInbounds = %v，期望成功为 %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestRedactPasswords(t *testing.T) {
+	previous := []InboundConfig{
+		{Type: "mixed", Tag: "mixed-in", Users: []InboundUser{{Username: "a", Password: "secret-a"}, {Username: "b", Password: "secret-b"}}},
+		{Type: "socks", Users: []InboundUser{{Username: "c", Password: "secret-c"}}},
+	}
+
+	redacted := RedactPasswords(previous)
+	for _, in := range redacted {
+		for _, user := range in.Users {
+			if user.Password != RedactedPassword {
+				t.Errorf("用户 %s 的密码未隐藏: %q", user.Username, user.Password)
+			}
+		}
+	}
+	if previous[0].Users[0].Password != "secret-a" {
+		t.Fatal("RedactPasswords 修改了原配置")
+	}
+
+	// 原样提交时恢复原密码，修改过的密码保留新值
+	redacted[0].Users[1].Password = "changed"
+	if err := RestorePasswords(redacted, previous); err != nil {
+		t.Fatalf("RestorePasswords 失败: %v", err)
+	}
+	want := []string{"secret-a", "changed", "secret-c"}
+	got := []string{redacted[0].Users[0].Password, redacted[0].Users[1].Password, redacted[1].Users[0].Password}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("恢复后的密码为 %v，期望 %v", got, want)
+			break
+		}
+	}
+
+	// 新用户不能使用隐藏的密码
+	added := RedactPasswords(previous)
+	added[0].Users = append(added[0].Users, InboundUser{Username: "new", Password: RedactedPassword})
+	if err := RestorePasswords(added, previous); err == nil {
+		t.Error("RestorePasswords 应当拒绝新用户的隐藏密码")
+	}
+}
@@ -0,0 +1,377 @@
+// Package core 管理本地安装的 sing-box 内核
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// DefaultMirror 默认下载地址模板
+const DefaultMirror = "https://github.com/SagerNet/sing-box/releases/download/v{version}/{file}"
+
+// Manager 内核管理器
+type Manager struct {
+	dir        string
+	mirror     string
+	checksums  map[string]string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// InstallOptions 安装选项
+type InstallOptions struct {
+	Archive string // 本地压缩包路径，为空时从镜像下载
+	SHA256  string // 压缩包的 SHA-256，为空时使用配置中预置的值
+}
+
+// InstalledCore 已安装的内核
+type InstalledCore struct {
+	Version     string    `json:"version"`
+	Path        string    `json:"path"`
+	InstalledAt time.Time `json:"installed_at"`
+	Size        int64     `json:"size"`
+}
+
+// NewManager 创建内核管理器
+func NewManager(cfg config.CoreConfig) *Manager {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(config.GetConfigDir(), "cores")
+	}
+	mirror := cfg.Mirror
+	if mirror == "" {
+		mirror = DefaultMirror
+	}
+
+	return &Manager{
+		dir:        dir,
+		mirror:     mirror,
+		checksums:  cfg.Checksums,
+		httpClient: &http.Client{Timeout: 10 * time.Minute},
+		logger:     logrus.New(),
+	}
+}
+
+// ArchiveName 返回当前平台的发布包文件名
+func ArchiveName(version string) string {
+	arch := runtime.GOARCH
+	if arch == "arm" {
+		arch = "armv7"
+	}
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("sing-box-%s-%s-%s.%s", version, runtime.GOOS, arch, ext)
+}
+
+// binaryName 返回当前平台的可执行文件名
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "sing-box.exe"
+	}
+	return "sing-box"
+}
+
+// BinaryPath 返回指定版本的可执行文件路径，未安装时返回错误
+func (m *Manager) BinaryPath(version string) (string, error) {
+	version, err := normalizeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(m.dir, version, binaryName())
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("sing-box %s 未安装", version)
+	}
+	return path, nil
+}
+
+// Installed 列出已安装的版本，按版本号从新到旧排序
+func (m *Manager) Installed() ([]InstalledCore, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取内核目录失败: %w", err)
+	}
+
+	var cores []InstalledCore
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(m.dir, entry.Name(), binaryName())
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		cores = append(cores, InstalledCore{
+			Version:     entry.Name(),
+			Path:        path,
+			InstalledAt: info.ModTime(),
+			Size:        info.Size(),
+		})
+	}
+
+	sort.Slice(cores, func(i, j int) bool {
+		return CompareVersions(cores[i].Version, cores[j].Version) > 0
+	})
+	return cores, nil
+}
+
+// Install 安装指定版本，压缩包必须通过 SHA-256 校验
+func (m *Manager) Install(version string, opts InstallOptions) (string, error) {
+	version, err := normalizeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckCompatibility(version); err != nil {
+		m.logger.Warnf("%v，安装后无法直接启动", err)
+	}
+
+	archiveName := ArchiveName(version)
+	if opts.Archive != "" {
+		archiveName = filepath.Base(opts.Archive)
+	}
+
+	// 没有可信的校验和时不下载
+	expected, err := m.expectedChecksum(archiveName, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// 准备压缩包
+	archivePath := opts.Archive
+	if archivePath == "" {
+		downloaded, err := m.download(version, archiveName)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(downloaded)
+		archivePath = downloaded
+	}
+
+	// 校验
+	actual, err := fileSHA256(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return "", fmt.Errorf("SHA-256 校验失败: 期望 %s，实际 %s", expected, actual)
+	}
+
+	// 解压到临时文件后再移动到版本目录
+	versionDir := filepath.Join(m.dir, version)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return "", fmt.Errorf("创建内核目录失败: %w", err)
+	}
+	target := filepath.Join(versionDir, binaryName())
+	tmp := target + ".tmp"
+	if err := extractBinary(archivePath, archiveName, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	// 安装前确认解压出的内核版本与期望一致
+	probed, err := ProbeVersion(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if CompareVersions(probed, version) != 0 {
+		os.Remove(tmp)
+		return "", fmt.Errorf("压缩包中的内核版本为 %s，与期望的 %s 不符", probed, version)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("安装内核失败: %w", err)
+	}
+
+	m.logger.Infof("已安装 sing-box %s: %s", version, target)
+	return target, nil
+}
+
+// Remove 删除指定版本
+func (m *Manager) Remove(version string) error {
+	version, err := normalizeVersion(version)
+	if err != nil {
+		return err
+	}
+	if _, err := m.BinaryPath(version); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(m.dir, version)); err != nil {
+		return fmt.Errorf("删除内核失败: %w", err)
+	}
+	return nil
+}
+
+// archiveURL 根据镜像模板生成下载地址
+func (m *Manager) archiveURL(version, archiveName string) string {
+	return strings.NewReplacer(
+		"{version}", version,
+		"{file}", archiveName,
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(m.mirror)
+}
+
+// download 下载压缩包到临时文件
+func (m *Manager) download(version, archiveName string) (string, error) {
+	url := m.archiveURL(version, archiveName)
+	m.logger.Infof("下载 sing-box %s: %s", version, url)
+
+	resp, err := m.httpClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("下载失败: %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return "", fmt.Errorf("创建内核目录失败: %w", err)
+	}
+	file, err := os.CreateTemp(m.dir, "download-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("下载失败: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// expectedChecksum 返回压缩包期望的 SHA-256，优先使用参数，其次使用配置中预置的值。
+// 与压缩包同源的 .sha256 文件不能证明完整性，sing-box 发布也不提供，因此不作为来源
+func (m *Manager) expectedChecksum(archiveName string, opts InstallOptions) (string, error) {
+	sum := opts.SHA256
+	if sum == "" {
+		sum = m.checksums[archiveName]
+	}
+	if sum == "" {
+		return "", fmt.Errorf("未配置 %s 的 SHA-256，请通过 --sha256 参数或配置 core.checksums 提供", archiveName)
+	}
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("无效的 SHA-256: %s", sum)
+	}
+	return sum, nil
+}
+
+// fileSHA256 计算文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("读取压缩包失败: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractBinary 从压缩包中提取 sing-box 可执行文件，按文件名判断压缩格式
+func extractBinary(archivePath, archiveName, target string) error {
+	if strings.HasSuffix(archiveName, ".zip") {
+		return extractFromZip(archivePath, target)
+	}
+	return extractFromTarGz(archivePath, target)
+}
+
+// extractFromTarGz 从 tar.gz 中提取
+func extractFromTarGz(archivePath, target string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("解压失败: %w", err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("解压失败: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName() {
+			return writeBinary(reader, target)
+		}
+	}
+
+	return fmt.Errorf("压缩包中没有 %s", binaryName())
+}
+
+// extractFromZip 从 zip 中提取
+func extractFromZip(archivePath, target string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || filepath.Base(file.Name) != binaryName() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("解压失败: %w", err)
+		}
+		defer rc.Close()
+		return writeBinary(rc, target)
+	}
+
+	return fmt.Errorf("压缩包中没有 %s", binaryName())
+}
+
+// writeBinary 写入可执行文件
+func writeBinary(r io.Reader, target string) error {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("写入内核失败: %w", err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("写入内核失败: %w", err)
+	}
+	return file.Close()
+}
+
+// SetLogger 设置日志记录器
+func (m *Manager) SetLogger(logger *logrus.Logger) {
+	m.logger = logger
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+func TestInstallRequiresChecksum(t *testing.T) {
+	var requests atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// 镜像提供的同源校验文件不能作为依据
+		w.Write([]byte(strings.Repeat("0", 64) + "  sing-box.tar.gz\n"))
+	}))
+	defer mirror.Close()
+
+	m := NewManager(config.CoreConfig{Dir: t.TempDir(), Mirror: mirror.URL + "/{version}/{file}"})
+	_, err := m.Install("1.10.1", InstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "未配置") {
+		t.Fatalf("Install = %v，期望提示未配置 SHA-256", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("没有校验和时请求了镜像 %d 次，期望不下载", n)
+	}
+}
+
+func TestExpectedChecksum(t *testing.T) {
+	archive := ArchiveName("1.10.1")
+	pinned := strings.Repeat("a", 64)
+	m := NewManager(config.CoreConfig{Dir: t.TempDir(), Checksums: map[string]string{archive: pinned}})
+
+	tests := []struct {
+		name string
+		opts InstallOptions
+		want string
+		ok   bool
+	}{
+		{"使用配置中预置的值", InstallOptions{}, pinned, true},
+		{"参数优先", InstallOptions{SHA256: strings.Repeat("b", 64)}, strings.Repeat("b", 64), true},
+		{"长度无效", InstallOptions{SHA256: "abc"}, "", false},
+		{"不是十六进制", InstallOptions{SHA256: strings.Repeat("z", 64)}, "", false},
+	}
+	for _, tt := range tests {
+		got, err := m.expectedChecksum(archive, tt.opts)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("%s: expectedChecksum = %q, %v，期望 %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := m.expectedChecksum("other.tar.gz", InstallOptions{}); err == nil {
+		t.Error("没有预置的发布包应当返回错误")
+	}
+}
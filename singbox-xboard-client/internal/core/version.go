@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinSupportedVersion 支持的最低 sing-box 版本（含）
+	MinSupportedVersion = "1.8.0"
+
+	// MaxSupportedVersion 支持的最高 sing-box 版本（不含），
+	// 1.13 起移除了生成配置仍在使用的 dns 出站和入站 sniff 字段
+	MaxSupportedVersion = "1.13.0"
+)
+
+var (
+	// versionPattern 匹配 `sing-box version` 的输出
+	versionPattern = regexp.MustCompile(`sing-box version (\S+)`)
+
+	// validVersionPattern 合法的版本号，如 1.8.0、1.9.0-beta.1 或 1.9.0+build.5
+	validVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?(\+[0-9A-Za-z.]+)?$`)
+)
+
+// normalizeVersion 去掉 v 前缀并校验版本号格式
+func normalizeVersion(version string) (string, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !validVersionPattern.MatchString(version) {
+		return "", fmt.Errorf("无效的版本号: %s", version)
+	}
+	return version, nil
+}
+
+// ProbeVersion 执行 `sing-box version` 获取内核版本
+func ProbeVersion(binary string) (string, error) {
+	output, err := exec.Command(binary, "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("执行 %s version 失败: %w", binary, err)
+	}
+
+	match := versionPattern.FindStringSubmatch(string(output))
+	if match == nil {
+		return "", fmt.Errorf("无法识别 sing-box 版本输出: %s", strings.TrimSpace(string(output)))
+	}
+	return match[1], nil
+}
+
+// CheckCompatibility 检查内核版本是否在支持范围内，不支持的版本线上的预发布版本同样不支持
+func CheckCompatibility(version string) error {
+	if CompareVersions(version, MinSupportedVersion) < 0 {
+		return fmt.Errorf("sing-box %s 过旧，最低需要 %s", version, MinSupportedVersion)
+	}
+	versionCore, _ := splitVersion(version)
+	maxCore, _ := splitVersion(MaxSupportedVersion)
+	if compareCore(versionCore, maxCore) >= 0 {
+		return fmt.Errorf("sing-box %s 尚未适配，请使用低于 %s 的版本", version, MaxSupportedVersion)
+	}
+	return nil
+}
+
+// CompareVersions 按语义化版本比较两个版本号，a<b 返回 -1，相等返回 0，a>b 返回 1；
+// 预发布版本（如 1.9.0-beta.1）低于对应的正式版本，构建信息（+ 之后的部分）不参与比较
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	if c := compareCore(aCore, bCore); c != 0 {
+		return c
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePrerelease(aPre, bPre)
+}
+
+// compareCore 比较主版本号
+func compareCore(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// comparePrerelease 逐个比较点分隔的预发布标识：数字按数值比较且低于非数字标识，
+// 其余按字符串比较，前面都相同时标识较少的版本较低
+func comparePrerelease(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if c := compareIdentifier(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier 比较单个预发布标识
+func compareIdentifier(a, b string) int {
+	aNum, aErr := strconv.ParseUint(a, 10, 64)
+	bNum, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if aNum == bNum {
+			return 0
+		}
+		if aNum < bNum {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// splitVersion 拆分为主版本号和预发布标识，丢弃构建信息
+func splitVersion(version string) ([3]int, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+
+	var pre string
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		pre = version[i+1:]
+		version = version[:i]
+	}
+
+	var parts [3]int
+	for i, part := range strings.SplitN(version, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts, pre
+}
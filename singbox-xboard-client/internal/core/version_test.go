@@ -0,0 +1,65 @@
+package core
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.8.0", "1.8.0", 0},
+		{"v1.8.0", "1.8.0", 0},
+		{"1.8.0", "1.9.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.9.0-beta.1", "1.9.0", -1},
+		{"1.9.0", "1.9.0-rc.1", 1},
+		{"1.9.0-beta.10", "1.9.0-beta.9", 1},
+		{"1.9.0-beta.2", "1.9.0-beta.10", -1},
+		{"1.9.0-alpha.1", "1.9.0-beta.1", -1},
+		{"1.9.0-beta", "1.9.0-beta.1", -1},
+		{"1.9.0-1", "1.9.0-beta", -1},
+		{"1.9.0+build.5", "1.9.0", 0},
+		{"1.9.0-beta.1+abc", "1.9.0-beta.1", 0},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d，期望 %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		version string
+		ok      bool
+	}{
+		{"1.7.9", false},
+		{"1.8.0-beta.1", false},
+		{"1.8.0", true},
+		{"1.12.9", true},
+		{"1.12.0+build.1", true},
+		{"1.13.0-beta.1", false},
+		{"1.13.0", false},
+		{"2.0.0", false},
+	}
+
+	for _, tt := range tests {
+		if err := CheckCompatibility(tt.version); (err == nil) != tt.ok {
+			t.Errorf("CheckCompatibility(%q) = %v，期望兼容为 %v", tt.version, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	for _, version := range []string{"1.8.0", "v1.9.0-beta.1", "1.9.0+build.5"} {
+		if _, err := normalizeVersion(version); err != nil {
+			t.Errorf("normalizeVersion(%q) 失败: %v", version, err)
+		}
+	}
+	for _, version := range []string{"", "1.8", "1.8.0-", "latest"} {
+		if _, err := normalizeVersion(version); err == nil {
+			t.Errorf("normalizeVersion(%q) 应当失败", version)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package health 记录节点延迟和失败样本，计算延迟分位数、丢包率和可用性评分
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+const (
+	// Window 参与统计的样本时间范围
+	Window = 24 * time.Hour
+
+	// maxSamples 每个节点保留的样本上限
+	maxSamples = 500
+
+	// MinSamples 样本数达到该值后才按评分排除节点
+	MinSamples = 3
+)
+
+// 评分参数：p95 延迟超过 latencyBase 后每多 latencyStep 扣 1 分，最多扣 maxLatencyPenalty 分
+const (
+	latencyBase       = 300
+	latencyStep       = 50
+	maxLatencyPenalty = 30
+)
+
+// rejoinMargin 被排除的节点评分需高出阈值该值才恢复，避免在阈值附近反复切换
+const rejoinMargin = 5
+
+// Sample 一次测速结果
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Delay int       `json:"delay"` // 延迟（毫秒），0 表示失败
+}
+
+// Stats 节点在统计范围内的健康状况
+type Stats struct {
+	Samples   int       `json:"samples"`
+	P50       int       `json:"p50"`   // 成功样本的延迟中位数（毫秒）
+	P95       int       `json:"p95"`   // 成功样本的 95 分位延迟（毫秒）
+	Loss      float64   `json:"loss"`  // 失败比例，0 到 1
+	Score     int       `json:"score"` // 可用性评分，0 到 100
+	LastDelay int       `json:"last_delay"`
+	LastCheck time.Time `json:"last_check"`
+}
+
+// Tracker 保存各节点的样本，持久化到配置目录下的 node-health.json
+type Tracker struct {
+	path   string
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	samples map[string][]Sample
+}
+
+// Open 加载已保存的样本，path 为空时使用配置目录下的 node-health.json
+func Open(path string) *Tracker {
+	if path == "" {
+		path = filepath.Join(config.GetConfigDir(), "node-health.json")
+	}
+	t := &Tracker{
+		path:    path,
+		logger:  logrus.New(),
+		samples: make(map[string][]Sample),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			t.logger.Warnf("读取节点健康记录失败: %v", err)
+		}
+		return t
+	}
+	if err := json.Unmarshal(data, &t.samples); err != nil {
+		t.logger.Warnf("解析节点健康记录失败: %v", err)
+		t.samples = make(map[string][]Sample)
+	}
+	return t
+}
+
+// SetLogger 设置日志记录器
+func (t *Tracker) SetLogger(logger *logrus.Logger) {
+	t.logger = logger
+}
+
+// Record 记录一次测速结果，delay 为 0 表示失败
+func (t *Tracker) Record(node string, delay int, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.samples[node], Sample{Time: at, Delay: delay})
+	t.samples[node] = trim(samples, at)
+}
+
+// Retain 只保留仍在订阅中的节点
+func (t *Tracker) Retain(nodes []string) {
+	keep := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		keep[node] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for node := range t.samples {
+		if !keep[node] {
+			delete(t.samples, node)
+		}
+	}
+}
+
+// Save 原子写入磁盘，写入中途退出不会截断已有的记录
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(t.samples)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Stats 返回节点的健康状况，没有样本时 ok 为 false
+func (t *Tracker) Stats(node string) (Stats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := trim(t.samples[node], time.Now())
+	if len(samples) == 0 {
+		return Stats{}, false
+	}
+	return compute(samples), true
+}
+
+// All 返回所有节点的健康状况
+func (t *Tracker) All() map[string]Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[string]Stats, len(t.samples))
+	for node, samples := range t.samples {
+		if samples = trim(samples, now); len(samples) > 0 {
+			result[node] = compute(samples)
+		}
+	}
+	return result
+}
+
+// Excluded 返回评分低于 minScore 的节点。previous 为上次排除的节点，
+// 它们需要评分达到 minScore 加上回差才恢复；样本不足的节点不排除
+func (t *Tracker) Excluded(minScore int, previous map[string]bool) map[string]bool {
+	excluded := make(map[string]bool)
+	if minScore <= 0 {
+		return excluded
+	}
+
+	for node, stats := range t.All() {
+		if stats.Samples < MinSamples {
+			continue
+		}
+		threshold := minScore
+		if previous[node] {
+			threshold += rejoinMargin
+		}
+		if stats.Score < threshold {
+			excluded[node] = true
+		}
+	}
+	return excluded
+}
+
+// trim 丢弃超出统计范围和数量上限的旧样本
+func trim(samples []Sample, now time.Time) []Sample {
+	cutoff := now.Add(-Window)
+	i := 0
+	for i < len(samples) && samples[i].Time.Before(cutoff) {
+		i++
+	}
+	if len(samples)-i > maxSamples {
+		i = len(samples) - maxSamples
+	}
+	return samples[i:]
+}
+
+// compute 根据样本计算健康状况
+func compute(samples []Sample) Stats {
+	var delays []int
+	for _, s := range samples {
+		if s.Delay > 0 {
+			delays = append(delays, s.Delay)
+		}
+	}
+	sort.Ints(delays)
+
+	last := samples[len(samples)-1]
+	stats := Stats{
+		Samples:   len(samples),
+		Loss:      float64(len(samples)-len(delays)) / float64(len(samples)),
+		LastDelay: last.Delay,
+		LastCheck: last.Time,
+	}
+	if len(delays) == 0 {
+		return stats
+	}
+
+	stats.P50 = percentile(delays, 0.5)
+	stats.P95 = percentile(delays, 0.95)
+
+	penalty := 0
+	if stats.P95 > latencyBase {
+		penalty = min((stats.P95-latencyBase)/latencyStep, maxLatencyPenalty)
+	}
+	stats.Score = max(int(math.Round((1-stats.Loss)*100))-penalty, 0)
+	return stats
+}
+
+// percentile 按最近秩法取已排序数据的分位数
+func percentile(sorted []int, p float64) int {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
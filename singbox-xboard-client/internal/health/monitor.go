@@ -0,0 +1,197 @@
+package health
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/render"
+)
+
+const (
+	// defaultInterval 未配置时的测试间隔
+	defaultInterval = 5 * time.Minute
+
+	// defaultTimeout 未配置时的测速超时
+	defaultTimeout = 3 * time.Second
+
+	// maxTimeout Clash API 客户端的请求超时为 5 秒，测速超时需留出余量
+	maxTimeout = 4 * time.Second
+
+	// concurrency 同时测速的节点数
+	concurrency = 8
+)
+
+// ErrNodeFailed 节点本身测速失败，如超时或无法连接
+var ErrNodeFailed = errors.New("节点测速失败")
+
+// Prober 提供节点列表和测速能力
+type Prober interface {
+	// IsRunning sing-box 是否在运行，未运行时跳过本轮测试
+	IsRunning() bool
+	// NodeTags 当前订阅节点的出站标签
+	NodeTags() ([]string, error)
+	// TestDelay 通过节点访问 testURL，返回延迟（毫秒）。节点失败时返回的错误需包含
+	// ErrNodeFailed，其他错误视为测速不可用（如 sing-box 正在重启），不计入节点记录
+	TestDelay(node, testURL string, timeout time.Duration) (int, error)
+}
+
+// result 一个节点的测速结果，delay 为 0 表示失败
+type result struct {
+	node  string
+	delay int
+	at    time.Time
+}
+
+// Monitor 定期测试所有节点并记录结果
+type Monitor struct {
+	tracker  *Tracker
+	prober   Prober
+	interval time.Duration
+	url      string
+	timeout  time.Duration
+	logger   *logrus.Logger
+
+	mu     sync.Mutex
+	hooks  []func()
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewMonitor 按配置创建定期测试器
+func NewMonitor(tracker *Tracker, prober Prober, cfg config.HealthConfig) *Monitor {
+	m := &Monitor{
+		tracker:  tracker,
+		prober:   prober,
+		interval: time.Duration(cfg.Interval) * time.Minute,
+		url:      cfg.URL,
+		timeout:  time.Duration(cfg.Timeout) * time.Millisecond,
+		logger:   logrus.New(),
+	}
+	if m.interval <= 0 {
+		m.interval = defaultInterval
+	}
+	if m.url == "" {
+		m.url = render.URLTestURL
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultTimeout
+	}
+	m.timeout = min(m.timeout, maxTimeout)
+	return m
+}
+
+// SetLogger 设置日志记录器
+func (m *Monitor) SetLogger(logger *logrus.Logger) {
+	m.logger = logger
+}
+
+// OnRound 注册每轮测试完成后的钩子
+func (m *Monitor) OnRound(hook func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Start 开始定期测试，首轮在一分钟内进行
+func (m *Monitor) Start() {
+	m.stopCh = make(chan struct{})
+	m.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(m.doneCh)
+
+		timer := time.NewTimer(min(m.interval, time.Minute))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				m.Probe()
+				timer.Reset(m.interval)
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定期测试，等待进行中的一轮结束
+func (m *Monitor) Stop() {
+	if m.stopCh == nil {
+		return
+	}
+	close(m.stopCh)
+	<-m.doneCh
+}
+
+// Probe 立即测试所有节点。sing-box 未运行时跳过；测速期间 sing-box 停止、重启或
+// Clash API 不可用时丢弃整轮结果，避免把这段时间的失败记在节点上
+func (m *Monitor) Probe() {
+	if !m.prober.IsRunning() {
+		return
+	}
+	nodes, err := m.prober.NodeTags()
+	if err != nil {
+		m.logger.Warnf("获取节点失败: %v", err)
+		return
+	}
+
+	var (
+		mu          sync.Mutex
+		results     = make([]result, 0, len(nodes))
+		unavailable error
+	)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(node string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			delay, err := m.prober.TestDelay(node, m.url, m.timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrNodeFailed):
+				m.logger.Debugf("节点 %s 测速失败: %v", node, err)
+				delay = 0
+			default:
+				unavailable = err
+				return
+			}
+			results = append(results, result{node: node, delay: delay, at: time.Now()})
+		}(node)
+	}
+	wg.Wait()
+
+	if unavailable == nil && !m.prober.IsRunning() {
+		unavailable = errors.New("sing-box 未运行")
+	}
+	if unavailable != nil {
+		m.logger.Infof("测速期间 sing-box 不可用，丢弃本轮健康检查: %v", unavailable)
+		return
+	}
+
+	for _, r := range results {
+		m.tracker.Record(r.node, r.delay, r.at)
+	}
+	m.tracker.Retain(nodes)
+	if err := m.tracker.Save(); err != nil {
+		m.logger.Warnf("保存节点健康记录失败: %v", err)
+	}
+
+	m.mu.Lock()
+	hooks := make([]func(), len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// fakeProber 按节点返回预设的延迟或错误
+type fakeProber struct {
+	mu      sync.Mutex
+	running bool
+	delays  map[string]int
+	errs    map[string]error
+	onProbe func() // 每次测速时调用，用于模拟测速期间 sing-box 停止
+}
+
+func (f *fakeProber) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+func (f *fakeProber) NodeTags() ([]string, error) {
+	return []string{"a", "b", "c"}, nil
+}
+
+func (f *fakeProber) TestDelay(node, testURL string, timeout time.Duration) (int, error) {
+	if f.onProbe != nil {
+		f.onProbe()
+	}
+	if err := f.errs[node]; err != nil {
+		return 0, err
+	}
+	return f.delays[node], nil
+}
+
+func newTestMonitor(t *testing.T, prober Prober) (*Monitor, *Tracker) {
+	t.Helper()
+	tracker := Open(filepath.Join(t.TempDir(), "node-health.json"))
+	m := NewMonitor(tracker, prober, config.HealthConfig{})
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m.SetLogger(logger)
+	return m, tracker
+}
+
+func TestProbeRecordsNodeFailures(t *testing.T) {
+	prober := &fakeProber{
+		running: true,
+		delays:  map[string]int{"a": 120, "b": 300},
+		errs:    map[string]error{"c": fmt.Errorf("%w: 超时", ErrNodeFailed)},
+	}
+	m, tracker := newTestMonitor(t, prober)
+
+	rounds := 0
+	m.OnRound(func() { rounds++ })
+	m.Probe()
+
+	if rounds != 1 {
+		t.Errorf("钩子调用了 %d 次，期望 1", rounds)
+	}
+	for node, want := range map[string]int{"a": 120, "b": 300, "c": 0} {
+		stats, ok := tracker.Stats(node)
+		if !ok || stats.Samples != 1 || stats.LastDelay != want {
+			t.Errorf("节点 %s 的记录为 %+v，期望 1 个延迟为 %d 的样本", node, stats, want)
+		}
+	}
+}
+
+func TestProbeSkipsUnavailable(t *testing.T) {
+	// 测速期间 sing-box 停止，测速本身没有返回错误
+	stopping := &fakeProber{running: true, delays: map[string]int{"a": 120, "b": 300, "c": 50}}
+	stopping.onProbe = func() {
+		stopping.mu.Lock()
+		stopping.running = false
+		stopping.mu.Unlock()
+	}
+
+	tests := []struct {
+		name   string
+		prober *fakeProber
+	}{
+		{"未运行", &fakeProber{}},
+		{"Clash API 不可用", &fakeProber{
+			running: true,
+			delays:  map[string]int{"a": 120, "b": 300},
+			errs:    map[string]error{"c": errors.New("sing-box 未运行")},
+		}},
+		{"测速期间停止", stopping},
+	}
+	for _, tt := range tests {
+		m, tracker := newTestMonitor(t, tt.prober)
+		rounds := 0
+		m.OnRound(func() { rounds++ })
+		m.Probe()
+
+		if rounds != 0 || len(tracker.All()) != 0 {
+			t.Errorf("%s: 钩子调用了 %d 次，记录了 %v，期望丢弃整轮", tt.name, rounds, tracker.All())
+		}
+	}
+}
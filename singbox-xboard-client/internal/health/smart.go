@@ -0,0 +1,327 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+const (
+	// defaultSmartInterval 未配置时重新选择的间隔
+	defaultSmartInterval = 10 * time.Minute
+
+	// defaultMargin 未配置时切换所需的最小得分差
+	defaultMargin = 10
+)
+
+// 各项得分的权重，未配置偏好地区时地区权重不参与计算
+const (
+	weightAvailability = 0.3
+	weightLatency      = 0.35
+	weightLoad         = 0.2
+	weightRegion       = 0.15
+)
+
+// 延迟得分：中位数不超过 fastLatency 得满分，达到 slowLatency 得 0 分
+const (
+	fastLatency = 100
+	slowLatency = 1000
+)
+
+// neutralScore 尚无测速记录时延迟和可用性的得分
+const neutralScore = 50
+
+// Candidate 参与选择的节点
+type Candidate struct {
+	xboard.NodeInfo
+	Tag      string `json:"tag,omitempty"`
+	Excluded bool   `json:"excluded,omitempty"` // 评分过低，不参与自动测速选择
+	Health   *Stats `json:"health,omitempty"`   // 尚无测速记录时为空
+}
+
+// Ranked 节点的综合得分
+type Ranked struct {
+	Tag          string  `json:"tag"`
+	Name         string  `json:"name"`
+	Score        float64 `json:"score"`
+	Availability float64 `json:"availability"`
+	Latency      float64 `json:"latency"`
+	Load         float64 `json:"load"`
+	Region       float64 `json:"region"`
+}
+
+// Decision 一次选择的结果
+type Decision struct {
+	Time     time.Time `json:"time"`
+	From     string    `json:"from,omitempty"`
+	To       string    `json:"to"`
+	Score    float64   `json:"score"`
+	Previous float64   `json:"previous"` // 原节点的得分，原节点不可用时为 0
+	Reason   string    `json:"reason"`
+}
+
+// Rank 按综合得分从高到低排列可用节点。离线和评分过低的节点不参与，
+// 除非所有节点都不可用
+func Rank(candidates []Candidate, regions []string) []Ranked {
+	var usable []Candidate
+	for _, c := range candidates {
+		if c.Tag != "" && c.Status != 0 && !c.Excluded {
+			usable = append(usable, c)
+		}
+	}
+	if len(usable) == 0 {
+		for _, c := range candidates {
+			if c.Tag != "" {
+				usable = append(usable, c)
+			}
+		}
+	}
+
+	ranked := make([]Ranked, 0, len(usable))
+	for _, c := range usable {
+		ranked = append(ranked, score(c, regions))
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// score 计算单个节点的各项得分，均为 0 到 100
+func score(c Candidate, regions []string) Ranked {
+	r := Ranked{
+		Tag:          c.Tag,
+		Name:         c.Name,
+		Availability: neutralScore,
+		Latency:      neutralScore,
+		Load:         clamp(100 - float64(c.Load)),
+	}
+	if c.Health != nil && c.Health.Samples > 0 {
+		r.Availability = float64(c.Health.Score)
+		r.Latency = 0
+		if c.Health.P50 > 0 {
+			r.Latency = math.Round(clamp(100 * float64(slowLatency-c.Health.P50) / (slowLatency - fastLatency)))
+		}
+	}
+
+	total := weightAvailability*r.Availability + weightLatency*r.Latency + weightLoad*r.Load
+	weights := weightAvailability + weightLatency + weightLoad
+	if len(regions) > 0 {
+		r.Region = regionScore(c, regions)
+		total += weightRegion * r.Region
+		weights += weightRegion
+	}
+	r.Score = math.Round(total/weights*10) / 10
+	return r
+}
+
+// regionScore 匹配第一个偏好地区得 100 分，之后依次递减，都不匹配得 0 分
+func regionScore(c Candidate, regions []string) float64 {
+	location := strings.ToLower(c.Location)
+	name := strings.ToLower(c.Name)
+	for i, region := range regions {
+		region = strings.ToLower(strings.TrimSpace(region))
+		if region == "" {
+			continue
+		}
+		if strings.Contains(location, region) || strings.Contains(name, region) {
+			return 100 * float64(len(regions)-i) / float64(len(regions))
+		}
+	}
+	return 0
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(100, v))
+}
+
+// Pick 从排名中选择节点。当前节点仍可用时，只有得分高出 margin 的节点才会取代它
+func Pick(ranked []Ranked, current string, margin int) (Decision, bool) {
+	if len(ranked) == 0 {
+		return Decision{}, false
+	}
+
+	best := ranked[0]
+	decision := Decision{From: current, To: best.Tag, Score: best.Score}
+	if best.Tag == current {
+		return decision, false
+	}
+
+	for _, r := range ranked {
+		if r.Tag != current {
+			continue
+		}
+		decision.Previous = r.Score
+		if best.Score-r.Score < float64(margin) {
+			return decision, false
+		}
+		decision.Reason = fmt.Sprintf("得分 %.1f 高于当前节点 %.1f", best.Score, r.Score)
+		return decision, true
+	}
+
+	decision.Reason = "当前未选择节点"
+	if current != "" {
+		decision.Reason = fmt.Sprintf("当前选择 %s 不是可用节点", current)
+	}
+	return decision, true
+}
+
+// SmartSource 提供候选节点和 select 组的读写
+type SmartSource interface {
+	// IsRunning sing-box 是否在运行，未运行时跳过本轮选择
+	IsRunning() bool
+	// Candidates 当前订阅节点及其健康状况
+	Candidates() ([]Candidate, error)
+	// Current select 组当前选择的出站
+	Current() (string, error)
+	// Select 在 select 组中选择出站
+	Select(tag string) error
+}
+
+// SmartStatus 最近一次选择的排名和切换记录
+type SmartStatus struct {
+	Enabled bool      `json:"enabled"`
+	Current string    `json:"current,omitempty"`
+	Checked time.Time `json:"checked,omitempty"`
+	Ranking []Ranked  `json:"ranking"`
+	Last    *Decision `json:"last,omitempty"` // 最近一次切换
+}
+
+// Smart 定期为 select 组重新选择节点
+type Smart struct {
+	source   SmartSource
+	interval time.Duration
+	regions  []string
+	margin   int
+	logger   *logrus.Logger
+
+	mu     sync.Mutex
+	status SmartStatus
+	hooks  []func(Decision)
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewSmart 按配置创建智能选择器
+func NewSmart(source SmartSource, cfg config.SmartConfig) *Smart {
+	s := &Smart{
+		source:   source,
+		interval: time.Duration(cfg.Interval) * time.Minute,
+		regions:  cfg.Regions,
+		margin:   cfg.Margin,
+		logger:   logrus.New(),
+		status:   SmartStatus{Enabled: true, Ranking: []Ranked{}},
+	}
+	if s.interval <= 0 {
+		s.interval = defaultSmartInterval
+	}
+	if s.margin <= 0 {
+		s.margin = defaultMargin
+	}
+	return s
+}
+
+// SetLogger 设置日志记录器
+func (s *Smart) SetLogger(logger *logrus.Logger) {
+	s.logger = logger
+}
+
+// OnSwitch 注册切换节点后的钩子
+func (s *Smart) OnSwitch(hook func(Decision)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Start 开始定期选择，首轮在一分钟内进行
+func (s *Smart) Start() {
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(s.doneCh)
+
+		timer := time.NewTimer(min(s.interval, time.Minute))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				if _, err := s.Run(); err != nil {
+					s.logger.Warnf("智能选择节点失败: %v", err)
+				}
+				timer.Reset(s.interval)
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定期选择
+func (s *Smart) Stop() {
+	if s.stopCh == nil {
+		return
+	}
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+// Status 返回最近一次选择的排名和切换记录
+func (s *Smart) Status() SmartStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Run 立即重新选择，切换了节点时返回切换记录。sing-box 未运行时跳过
+func (s *Smart) Run() (*Decision, error) {
+	if !s.source.IsRunning() {
+		return nil, nil
+	}
+	candidates, err := s.source.Candidates()
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.source.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := Rank(candidates, s.regions)
+	decision, change := Pick(ranked, current, s.margin)
+
+	s.mu.Lock()
+	s.status.Current = current
+	s.status.Checked = time.Now()
+	s.status.Ranking = ranked
+	s.mu.Unlock()
+
+	if !change {
+		return nil, nil
+	}
+	if err := s.source.Select(decision.To); err != nil {
+		return nil, err
+	}
+	decision.Time = time.Now()
+	s.logger.Infof("智能选择节点: %s -> %s（%s）", current, decision.To, decision.Reason)
+
+	s.mu.Lock()
+	s.status.Current = decision.To
+	s.status.Last = &decision
+	hooks := make([]func(Decision), len(s.hooks))
+	copy(hooks, s.hooks)
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(decision)
+	}
+	return &decision, nil
+}
@@ -0,0 +1,184 @@
+package health
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+func candidate(tag, location string, status, load, p50, score int) Candidate {
+	c := Candidate{
+		NodeInfo: xboard.NodeInfo{Name: tag, Status: status, Load: load, Location: location},
+		Tag:      tag,
+	}
+	if p50 >= 0 {
+		c.Health = &Stats{Samples: 10, P50: p50, Score: score}
+	}
+	return c
+}
+
+func tags(ranked []Ranked) []string {
+	result := make([]string, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.Tag
+	}
+	return result
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRank(t *testing.T) {
+	fast := candidate("fast", "HK", 1, 0, 100, 100)
+	slow := candidate("slow", "JP", 1, 0, 550, 100)
+	unknown := candidate("unknown", "US", 1, 0, -1, 0)
+	offline := candidate("offline", "HK", 0, 0, 50, 100)
+	excluded := candidate("excluded", "HK", 1, 0, 50, 100)
+	excluded.Excluded = true
+	untagged := candidate("", "HK", 1, 0, 50, 100)
+
+	ranked := Rank([]Candidate{unknown, slow, offline, fast, excluded, untagged}, nil)
+	if want := []string{"fast", "slow", "unknown"}; !equalTags(tags(ranked), want) {
+		t.Fatalf("Rank = %v，期望 %v", tags(ranked), want)
+	}
+	if ranked[0].Score != 100 || ranked[0].Latency != 100 || ranked[0].Region != 0 {
+		t.Errorf("fast 的得分为 %+v，期望 100 分且不计地区", ranked[0])
+	}
+	if ranked[1].Latency != 50 {
+		t.Errorf("slow 的延迟得分为 %v，期望 50", ranked[1].Latency)
+	}
+	if ranked[2].Availability != neutralScore || ranked[2].Latency != neutralScore {
+		t.Errorf("无测速记录的节点得分为 %+v，期望中性分", ranked[2])
+	}
+
+	// 其他条件相同时偏好地区提高排名
+	japan := candidate("japan", "JP", 1, 0, 100, 100)
+	ranked = Rank([]Candidate{fast, japan}, []string{"jp", "hk"})
+	if want := []string{"japan", "fast"}; !equalTags(tags(ranked), want) {
+		t.Errorf("偏好 jp 时 Rank = %v，期望 %v", tags(ranked), want)
+	}
+	if ranked[0].Region != 100 || ranked[1].Region != 50 {
+		t.Errorf("地区得分为 %v、%v，期望 100、50", ranked[0].Region, ranked[1].Region)
+	}
+
+	// 所有节点都不可用时仍参与排名
+	ranked = Rank([]Candidate{offline, excluded, untagged}, nil)
+	if want := []string{"offline", "excluded"}; !equalTags(tags(ranked), want) {
+		t.Errorf("全部不可用时 Rank = %v，期望 %v", tags(ranked), want)
+	}
+
+	// 负载越高得分越低
+	busy := candidate("busy", "HK", 1, 90, 100, 100)
+	ranked = Rank([]Candidate{busy, fast}, nil)
+	if ranked[0].Tag != "fast" || ranked[1].Load != 10 {
+		t.Errorf("Rank = %+v，期望高负载节点排在后面", ranked)
+	}
+}
+
+func TestPick(t *testing.T) {
+	ranked := []Ranked{
+		{Tag: "a", Score: 90},
+		{Tag: "b", Score: 85},
+		{Tag: "c", Score: 70},
+	}
+
+	tests := []struct {
+		name     string
+		ranked   []Ranked
+		current  string
+		margin   int
+		change   bool
+		previous float64
+	}{
+		{"没有节点", nil, "a", 10, false, 0},
+		{"当前已是最佳", ranked, "a", 10, false, 0},
+		{"差距小于阈值时保持", ranked, "b", 10, false, 85},
+		{"差距等于阈值时切换", ranked, "c", 20, true, 70},
+		{"差距超过阈值时切换", ranked, "c", 10, true, 70},
+		{"当前节点不可用", ranked, "gone", 10, true, 0},
+		{"尚未选择", ranked, "", 10, true, 0},
+	}
+	for _, tt := range tests {
+		decision, change := Pick(tt.ranked, tt.current, tt.margin)
+		if change != tt.change {
+			t.Errorf("%s: 切换为 %v，期望 %v", tt.name, change, tt.change)
+			continue
+		}
+		if decision.Previous != tt.previous {
+			t.Errorf("%s: 原节点得分为 %v，期望 %v", tt.name, decision.Previous, tt.previous)
+		}
+		if change && (decision.To != "a" || decision.From != tt.current || decision.Reason == "") {
+			t.Errorf("%s: 切换记录为 %+v", tt.name, decision)
+		}
+	}
+}
+
+// fakeSource 记录选择的节点
+type fakeSource struct {
+	running    bool
+	candidates []Candidate
+	current    string
+	selected   []string
+}
+
+func (f *fakeSource) IsRunning() bool                  { return f.running }
+func (f *fakeSource) Candidates() ([]Candidate, error) { return f.candidates, nil }
+func (f *fakeSource) Current() (string, error)         { return f.current, nil }
+
+func (f *fakeSource) Select(tag string) error {
+	f.selected = append(f.selected, tag)
+	f.current = tag
+	return nil
+}
+
+func TestSmartRun(t *testing.T) {
+	source := &fakeSource{
+		candidates: []Candidate{
+			candidate("fast", "HK", 1, 0, 100, 100),
+			candidate("slow", "JP", 1, 0, 900, 60),
+		},
+		current: "slow",
+	}
+	smart := NewSmart(source, config.SmartConfig{})
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	smart.SetLogger(logger)
+
+	var switched []Decision
+	smart.OnSwitch(func(d Decision) { switched = append(switched, d) })
+
+	// sing-box 未运行时跳过
+	if decision, err := smart.Run(); decision != nil || err != nil || len(source.selected) != 0 {
+		t.Fatalf("未运行时 Run = %v, %v，选择了 %v", decision, err, source.selected)
+	}
+
+	source.running = true
+	decision, err := smart.Run()
+	if err != nil || decision == nil || decision.To != "fast" {
+		t.Fatalf("Run = %+v, %v，期望切换到 fast", decision, err)
+	}
+	if len(source.selected) != 1 || len(switched) != 1 {
+		t.Errorf("选择了 %v，钩子收到 %d 次切换", source.selected, len(switched))
+	}
+	status := smart.Status()
+	if status.Current != "fast" || status.Last == nil || len(status.Ranking) != 2 {
+		t.Errorf("Status = %+v", status)
+	}
+
+	// 已是最佳节点时不再切换
+	if decision, err := smart.Run(); decision != nil || err != nil || len(source.selected) != 1 {
+		t.Errorf("再次 Run = %v, %v，选择了 %v", decision, err, source.selected)
+	}
+}
@@ -0,0 +1,213 @@
+// Package metrics 以 Prometheus 格式导出客户端运行指标
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+	"github.com/your-username/singbox-xboard-client/internal/subscription"
+	"github.com/your-username/singbox-xboard-client/internal/traffic"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// namespace 所有指标的前缀
+const namespace = "singbox_xboard"
+
+// nonNodeTypes 不属于节点的出站类型，不导出延迟
+var nonNodeTypes = map[string]bool{
+	"Direct": true,
+	"Block":  true,
+	"Reject": true,
+	"DNS":    true,
+}
+
+// 抓取时读取的指标
+var (
+	upDesc = prometheus.NewDesc(
+		namespace+"_singbox_up", "sing-box 是否在运行", nil, nil)
+	activeConnectionsDesc = prometheus.NewDesc(
+		namespace+"_active_connections", "当前活动连接数", nil, nil)
+	latencyDesc = prometheus.NewDesc(
+		namespace+"_node_latency_milliseconds", "节点最近一次测速的延迟", []string{"node"}, nil)
+	fetchDesc = prometheus.NewDesc(
+		namespace+"_subscription_fetches_total", "订阅拉取次数", []string{"result"}, nil)
+	fetchTimestampDesc = prometheus.NewDesc(
+		namespace+"_subscription_last_success_timestamp_seconds", "最近一次成功拉取订阅的时间", nil, nil)
+	fetchAgeDesc = prometheus.NewDesc(
+		namespace+"_subscription_age_seconds", "距最近一次成功拉取订阅的时长", nil, nil)
+)
+
+// Metrics 指标集合。流量、重启次数和用户信息由钩子推送，其余在抓取时读取
+type Metrics struct {
+	registry *prometheus.Registry
+	singbox  *singbox.Manager
+	sub      *subscription.Manager
+	meter    *traffic.Meter
+
+	mu     sync.Mutex
+	active map[string]bool // 活动连接 ID
+
+	bytes    *prometheus.CounterVec
+	restarts prometheus.Counter
+	exits    prometheus.Counter
+	quota    *prometheus.GaugeVec
+	expire   prometheus.Gauge
+}
+
+// New 创建指标集合
+func New(sb *singbox.Manager, sub *subscription.Manager) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		singbox:  sb,
+		sub:      sub,
+		meter:    traffic.NewMeter(),
+		active:   make(map[string]bool),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "outbound_bytes_total",
+			Help:      "各出站的流量",
+		}, []string{"outbound", "direction"}),
+		restarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "singbox_restarts_total",
+			Help:      "sing-box 意外退出后自动重启的次数",
+		}),
+		exits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "singbox_unexpected_exits_total",
+			Help:      "sing-box 意外退出的次数",
+		}),
+		quota: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "quota_bytes",
+			Help:      "订阅流量，type 为 upload、download、total 或 remaining",
+		}, []string{"type"}),
+		expire: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "subscription_expire_timestamp_seconds",
+			Help:      "订阅到期时间，永不过期时为 0",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.bytes, m.restarts, m.exits, m.quota, m.expire,
+		collector{m},
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler 返回 /metrics 的处理器
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Record 记录一次连接变化
+func (m *Metrics) Record(update singbox.ConnectionsUpdate) {
+	m.mu.Lock()
+	for _, conn := range update.Added {
+		m.active[conn.ID] = true
+	}
+	for _, conn := range update.Closed {
+		delete(m.active, conn.ID)
+	}
+	m.mu.Unlock()
+
+	m.meter.Record(update)
+	for outbound, delta := range m.meter.Take() {
+		m.bytes.WithLabelValues(outbound, "upload").Add(float64(delta.Upload))
+		m.bytes.WithLabelValues(outbound, "download").Add(float64(delta.Download))
+	}
+}
+
+// HandleEvent 记录 sing-box 生命周期事件
+func (m *Metrics) HandleEvent(event singbox.Event) {
+	switch event.Type {
+	case singbox.EventExited:
+		m.exits.Inc()
+	case singbox.EventRestarting:
+		m.restarts.Inc()
+	case singbox.EventStopped:
+		// 手动停止时连接跟踪器可能来不及报告关闭的连接
+		m.mu.Lock()
+		m.active = make(map[string]bool)
+		m.mu.Unlock()
+	}
+}
+
+// SetUserInfo 更新订阅流量和到期时间
+func (m *Metrics) SetUserInfo(info *xboard.UserInfo) {
+	m.quota.WithLabelValues("upload").Set(float64(info.Upload))
+	m.quota.WithLabelValues("download").Set(float64(info.Download))
+	m.quota.WithLabelValues("total").Set(float64(info.Total))
+
+	// 总量为 0 表示不限流量，不导出剩余流量
+	if info.Total > 0 {
+		m.quota.WithLabelValues("remaining").Set(float64(max(info.Total-info.Upload-info.Download, 0)))
+	} else {
+		m.quota.DeleteLabelValues("remaining")
+	}
+
+	if info.ExpireTime.IsZero() {
+		m.expire.Set(0)
+	} else {
+		m.expire.Set(float64(info.ExpireTime.Unix()))
+	}
+}
+
+// collector 抓取时读取 sing-box 和订阅状态
+type collector struct {
+	m *Metrics
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- activeConnectionsDesc
+	ch <- latencyDesc
+	ch <- fetchDesc
+	ch <- fetchTimestampDesc
+	ch <- fetchAgeDesc
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	running := c.m.singbox.IsRunning()
+	up := 0.0
+	if running {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up)
+
+	c.m.mu.Lock()
+	active := len(c.m.active)
+	c.m.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(activeConnectionsDesc, prometheus.GaugeValue, float64(active))
+
+	// 延迟取自 urltest 出站组的测速记录，测速失败的节点不导出
+	if running {
+		if client, err := c.m.singbox.ClashAPI(); err == nil {
+			if proxies, err := client.GetProxies(); err == nil {
+				for name, proxy := range proxies {
+					if len(proxy.All) > 0 || nonNodeTypes[proxy.Type] {
+						continue
+					}
+					if delay := proxy.LastDelay(); delay > 0 {
+						ch <- prometheus.MustNewConstMetric(latencyDesc, prometheus.GaugeValue, float64(delay), name)
+					}
+				}
+			}
+		}
+	}
+
+	stats := c.m.sub.FetchStats()
+	ch <- prometheus.MustNewConstMetric(fetchDesc, prometheus.CounterValue, float64(stats.Success), "success")
+	ch <- prometheus.MustNewConstMetric(fetchDesc, prometheus.CounterValue, float64(stats.Failure), "failure")
+	if !stats.LastSuccess.IsZero() {
+		ch <- prometheus.MustNewConstMetric(fetchTimestampDesc, prometheus.GaugeValue, float64(stats.LastSuccess.Unix()))
+		ch <- prometheus.MustNewConstMetric(fetchAgeDesc, prometheus.GaugeValue, time.Since(stats.LastSuccess).Seconds())
+	}
+}
@@ -0,0 +1,304 @@
+// Package policy 按 cron 表达式定时切换规则模式、节点和规则集
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/render"
+)
+
+// maxEvents 保留的执行记录条数
+const maxEvents = 100
+
+// 触发方式
+const (
+	TriggerSchedule = "schedule" // 定时触发
+	TriggerManual   = "manual"   // 手动触发
+)
+
+// Actions 策略可执行的动作
+type Actions interface {
+	// SetMode 切换规则模式
+	SetMode(mode string) error
+	// SelectOutbound 在 selector 出站组中选择出站
+	SelectOutbound(selector, outbound string) error
+	// SetRuleSets 启用和停用规则集
+	SetRuleSets(enable, disable []string) error
+}
+
+// Event 一次策略执行记录
+type Event struct {
+	Time    time.Time `json:"time"`
+	Policy  string    `json:"policy"`
+	Trigger string    `json:"trigger"`
+	Actions []string  `json:"actions"` // 已完成的动作
+	Error   string    `json:"error,omitempty"`
+}
+
+// Status 策略及其执行情况
+type Status struct {
+	config.PolicyConfig
+	Next    *time.Time `json:"next,omitempty"`     // 下次定时执行的时间，停用时为空
+	LastRun *Event     `json:"last_run,omitempty"` // 最近一次执行记录
+}
+
+// Engine 策略引擎
+type Engine struct {
+	actions Actions
+	logger  *logrus.Logger
+
+	mu       sync.Mutex
+	cron     *cron.Cron
+	policies []config.PolicyConfig
+	entries  map[string]cron.EntryID
+	events   []Event // 按时间顺序
+	hooks    []func(Event)
+
+	runMu sync.Mutex // 串行执行策略，避免动作交错
+}
+
+// NewEngine 创建策略引擎，调用 Load 后开始定时执行
+func NewEngine(actions Actions) *Engine {
+	return &Engine{
+		actions: actions,
+		logger:  logrus.New(),
+		entries: make(map[string]cron.EntryID),
+	}
+}
+
+// SetLogger 设置日志记录器
+func (e *Engine) SetLogger(logger *logrus.Logger) {
+	e.logger = logger
+}
+
+// OnEvent 注册执行记录钩子
+func (e *Engine) OnEvent(hook func(Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hooks = append(e.hooks, hook)
+}
+
+// Validate 校验策略配置
+func Validate(policies []config.PolicyConfig) error {
+	names := make(map[string]bool, len(policies))
+	for i, p := range policies {
+		if p.Name == "" {
+			return fmt.Errorf("第 %d 条策略缺少名称", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("策略名称重复: %s", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := validate(p); err != nil {
+			return fmt.Errorf("策略 %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate 校验单条策略
+func validate(p config.PolicyConfig) error {
+	if _, err := cron.ParseStandard(p.Schedule); err != nil {
+		return fmt.Errorf("cron 表达式无效: %w", err)
+	}
+	if p.Mode == "" && p.Outbound == "" && len(p.EnableRuleSets) == 0 && len(p.DisableRuleSets) == 0 {
+		return fmt.Errorf("没有要执行的动作")
+	}
+	if p.Mode != "" {
+		if err := config.ValidateMode(p.Mode); err != nil {
+			return err
+		}
+	}
+	if p.Selector != "" && p.Outbound == "" {
+		return fmt.Errorf("设置了 selector 但缺少 outbound")
+	}
+
+	enable := make(map[string]bool, len(p.EnableRuleSets))
+	for _, tag := range p.EnableRuleSets {
+		enable[tag] = true
+	}
+	for _, tag := range p.DisableRuleSets {
+		if enable[tag] {
+			return fmt.Errorf("规则集 %s 不能同时启用和停用", tag)
+		}
+	}
+	return nil
+}
+
+// Load 校验并按新配置重新安排定时任务
+func (e *Engine) Load(policies []config.PolicyConfig) error {
+	if err := Validate(policies); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cron != nil {
+		e.cron.Stop()
+	}
+
+	e.cron = cron.New()
+	e.policies = append([]config.PolicyConfig(nil), policies...)
+	e.entries = make(map[string]cron.EntryID)
+	for _, p := range e.policies {
+		if p.Disabled {
+			continue
+		}
+		p := p
+		id, err := e.cron.AddFunc(p.Schedule, func() {
+			e.execute(p, TriggerSchedule)
+		})
+		if err != nil {
+			return fmt.Errorf("策略 %s: %w", p.Name, err)
+		}
+		e.entries[p.Name] = id
+	}
+	e.cron.Start()
+
+	if len(e.entries) > 0 {
+		e.logger.Infof("已加载 %d 条定时策略", len(e.entries))
+	}
+	return nil
+}
+
+// Stop 停止定时执行
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cron != nil {
+		e.cron.Stop()
+		e.cron = nil
+	}
+}
+
+// List 返回所有策略及其下次执行时间
+func (e *Engine) List() []Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]Status, 0, len(e.policies))
+	for _, p := range e.policies {
+		status := Status{PolicyConfig: p}
+		if id, ok := e.entries[p.Name]; ok && e.cron != nil {
+			if next := e.cron.Entry(id).Next; !next.IsZero() {
+				status.Next = &next
+			}
+		}
+		for i := len(e.events) - 1; i >= 0; i-- {
+			if e.events[i].Policy == p.Name {
+				event := e.events[i]
+				status.LastRun = &event
+				break
+			}
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// Events 返回最近的执行记录，最新的在前
+func (e *Engine) Events() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]Event, len(e.events))
+	for i, event := range e.events {
+		result[len(e.events)-1-i] = event
+	}
+	return result
+}
+
+// Run 立即执行指定策略，停用的策略也可以手动执行
+func (e *Engine) Run(name string) (Event, error) {
+	e.mu.Lock()
+	var policy *config.PolicyConfig
+	for i := range e.policies {
+		if e.policies[i].Name == name {
+			p := e.policies[i]
+			policy = &p
+			break
+		}
+	}
+	e.mu.Unlock()
+
+	if policy == nil {
+		return Event{}, fmt.Errorf("策略不存在: %s", name)
+	}
+	return e.execute(*policy, TriggerManual), nil
+}
+
+// execute 执行策略的动作：先切换规则集（可能重新生成配置），再切换规则模式和节点
+func (e *Engine) execute(p config.PolicyConfig, trigger string) Event {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+
+	event := Event{
+		Time:    time.Now(),
+		Policy:  p.Name,
+		Trigger: trigger,
+		Actions: []string{},
+	}
+
+	err := func() error {
+		if len(p.EnableRuleSets) > 0 || len(p.DisableRuleSets) > 0 {
+			if err := e.actions.SetRuleSets(p.EnableRuleSets, p.DisableRuleSets); err != nil {
+				return fmt.Errorf("切换规则集失败: %w", err)
+			}
+			if len(p.EnableRuleSets) > 0 {
+				event.Actions = append(event.Actions, "启用规则集 "+strings.Join(p.EnableRuleSets, ", "))
+			}
+			if len(p.DisableRuleSets) > 0 {
+				event.Actions = append(event.Actions, "停用规则集 "+strings.Join(p.DisableRuleSets, ", "))
+			}
+		}
+
+		if p.Mode != "" {
+			if err := e.actions.SetMode(p.Mode); err != nil {
+				return fmt.Errorf("切换规则模式失败: %w", err)
+			}
+			event.Actions = append(event.Actions, "规则模式切换为 "+p.Mode)
+		}
+
+		if p.Outbound != "" {
+			selector := p.Selector
+			if selector == "" {
+				selector = render.TagProxy
+			}
+			if err := e.actions.SelectOutbound(selector, p.Outbound); err != nil {
+				return err
+			}
+			event.Actions = append(event.Actions, selector+" 切换为 "+p.Outbound)
+		}
+		return nil
+	}()
+
+	if err != nil {
+		event.Error = err.Error()
+		e.logger.Warnf("执行策略 %s 失败: %v", p.Name, err)
+	} else {
+		e.logger.Infof("已执行策略 %s: %s", p.Name, strings.Join(event.Actions, "，"))
+	}
+
+	e.mu.Lock()
+	e.events = append(e.events, event)
+	if len(e.events) > maxEvents {
+		e.events = e.events[len(e.events)-maxEvents:]
+	}
+	hooks := make([]func(Event), len(e.hooks))
+	copy(hooks, e.hooks)
+	e.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+	return event
+}
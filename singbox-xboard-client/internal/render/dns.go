@@ -0,0 +1,383 @@
+package render
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/ruleset"
+)
+
+// 预设 DNS 规则使用的服务器标签
+const (
+	dnsRemote = "remote"
+	dnsLocal  = "local"
+	dnsFakeIP = "fakeip"
+)
+
+// hostsServerVersion 起支持新格式的 hosts DNS 服务器
+const hostsServerVersion = "1.12.0"
+
+// clientSubnetVersion 起支持 client_subnet
+const clientSubnetVersion = "1.9.0"
+
+// dnsSchemes DNS 服务器类型对应的地址前缀
+var dnsSchemes = map[string]string{
+	"udp":   "udp://",
+	"tcp":   "tcp://",
+	"tls":   "tls://",
+	"quic":  "quic://",
+	"https": "https://",
+	"h3":    "h3://",
+}
+
+// dnsQueryTypes 允许按名称匹配的查询类型
+var dnsQueryTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true,
+	"SOA": true, "SRV": true, "TXT": true, "HTTPS": true, "SVCB": true, "ANY": true,
+}
+
+// dns 生成 DNS 配置，用户规则优先于 FakeIP 和预设的国内外分流规则
+func (r *Renderer) dns(refs ruleRefs) (map[string]interface{}, error) {
+	cfg := r.cfg.Singbox.DNS
+	if len(cfg.Servers) == 0 {
+		return nil, fmt.Errorf("至少需要一个 DNS 服务器")
+	}
+
+	// 先收集标签，服务器之间可以通过 address_resolver 互相引用
+	tags := make(map[string]bool, len(cfg.Servers))
+	for _, server := range cfg.Servers {
+		if server.Tag == "" {
+			return nil, fmt.Errorf("DNS 服务器缺少标签")
+		}
+		if tags[server.Tag] || (server.Tag == dnsFakeIP && cfg.FakeIP.Enabled) {
+			return nil, fmt.Errorf("DNS 服务器标签重复: %s", server.Tag)
+		}
+		tags[server.Tag] = true
+	}
+
+	servers := make([]map[string]interface{}, 0, len(cfg.Servers)+1)
+	for _, server := range cfg.Servers {
+		entry, err := r.dnsServer(server, tags, refs)
+		if err != nil {
+			return nil, fmt.Errorf("DNS 服务器 %s: %w", server.Tag, err)
+		}
+		servers = append(servers, entry)
+	}
+
+	final := cfg.Final
+	if final == "" {
+		final = cfg.Servers[0].Tag
+	}
+	if !tags[final] {
+		return nil, fmt.Errorf("默认 DNS 服务器不存在: %s", final)
+	}
+
+	// 全局和直连模式下分别固定使用远程和本地 DNS
+	var rules []map[string]interface{}
+	if tags[dnsLocal] {
+		rules = append(rules, map[string]interface{}{
+			"clash_mode": config.ModeDirect,
+			"server":     dnsLocal,
+		})
+	}
+	if tags[dnsRemote] {
+		rules = append(rules, map[string]interface{}{
+			"clash_mode": config.ModeGlobal,
+			"server":     dnsRemote,
+		})
+	}
+
+	if cfg.FakeIP.Enabled {
+		tags[dnsFakeIP] = true
+	}
+	for i, rule := range cfg.Rules {
+		if refs.anyDisabled(rule.RuleSet) {
+			continue
+		}
+		entry, err := r.dnsRule(rule, tags, refs)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条 DNS 规则: %w", i+1, err)
+		}
+		rules = append(rules, entry)
+	}
+
+	var fakeip map[string]interface{}
+	if cfg.FakeIP.Enabled {
+		var fakeRules []map[string]interface{}
+		var err error
+		fakeip, fakeRules, err = fakeIP(cfg.FakeIP, final, refs)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, map[string]interface{}{
+			"tag":     dnsFakeIP,
+			"address": "fakeip",
+		})
+		rules = append(rules, fakeRules...)
+	}
+
+	if tags[dnsLocal] {
+		rules = append(rules, map[string]interface{}{
+			"rule_set": []string{ruleset.GeositeCN},
+			"server":   dnsLocal,
+		})
+	}
+	if tags[dnsRemote] {
+		rules = append(rules, map[string]interface{}{
+			"rule_set": []string{ruleset.GeositeNotCN},
+			"server":   dnsRemote,
+		})
+	}
+
+	result := map[string]interface{}{
+		"servers": servers,
+		"final":   final,
+	}
+	if len(rules) > 0 {
+		result["rules"] = rules
+	}
+	if cfg.Strategy != "" {
+		if err := checkStrategy(cfg.Strategy); err != nil {
+			return nil, err
+		}
+		result["strategy"] = cfg.Strategy
+	}
+	if cfg.DisableCache {
+		result["disable_cache"] = true
+	}
+	if cfg.ClientSubnet != "" {
+		if err := r.checkClientSubnet(cfg.ClientSubnet); err != nil {
+			return nil, err
+		}
+		result["client_subnet"] = cfg.ClientSubnet
+	}
+	if fakeip != nil {
+		result["fakeip"] = fakeip
+		// FakeIP 与真实解析结果分开缓存，避免切换后返回错误地址
+		result["independent_cache"] = true
+	}
+	return result, nil
+}
+
+// dnsServer 生成单个 DNS 服务器
+func (r *Renderer) dnsServer(server config.DNSServer, tags map[string]bool, refs ruleRefs) (map[string]interface{}, error) {
+	if server.Type == "hosts" {
+		return r.hostsServer(server)
+	}
+
+	address, err := dnsAddress(server)
+	if err != nil {
+		return nil, err
+	}
+	entry := map[string]interface{}{
+		"tag":     server.Tag,
+		"address": address,
+	}
+
+	if server.Detour != "" {
+		if !refs.outbounds[server.Detour] {
+			return nil, fmt.Errorf("出站不存在: %s", server.Detour)
+		}
+		entry["detour"] = server.Detour
+	}
+
+	if server.AddressResolver != "" {
+		if server.AddressResolver == server.Tag || !tags[server.AddressResolver] {
+			return nil, fmt.Errorf("address_resolver 无效: %s", server.AddressResolver)
+		}
+		entry["address_resolver"] = server.AddressResolver
+	} else if host := dnsHost(address); host != "" {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return nil, fmt.Errorf("地址 %s 为域名，需要设置 address_resolver", host)
+		}
+	}
+
+	if server.Strategy != "" {
+		if err := checkStrategy(server.Strategy); err != nil {
+			return nil, err
+		}
+		entry["strategy"] = server.Strategy
+	}
+	if server.ClientSubnet != "" {
+		if err := r.checkClientSubnet(server.ClientSubnet); err != nil {
+			return nil, err
+		}
+		entry["client_subnet"] = server.ClientSubnet
+	}
+	return entry, nil
+}
+
+// hostsServer 生成静态 hosts 服务器，只有新格式支持
+func (r *Renderer) hostsServer(server config.DNSServer) (map[string]interface{}, error) {
+	if !r.atLeast(hostsServerVersion) {
+		return nil, fmt.Errorf("hosts 服务器需要 sing-box %s 及以上", hostsServerVersion)
+	}
+	if len(server.Hosts) == 0 && len(server.HostsPath) == 0 {
+		return nil, fmt.Errorf("hosts 服务器缺少记录")
+	}
+	for domain, addresses := range server.Hosts {
+		for _, address := range addresses {
+			if _, err := netip.ParseAddr(address); err != nil {
+				return nil, fmt.Errorf("%s 的地址无效: %s", domain, address)
+			}
+		}
+	}
+
+	entry := map[string]interface{}{
+		"type": "hosts",
+		"tag":  server.Tag,
+	}
+	if len(server.HostsPath) > 0 {
+		entry["path"] = server.HostsPath
+	}
+	if len(server.Hosts) > 0 {
+		entry["predefined"] = server.Hosts
+	}
+	return entry, nil
+}
+
+// dnsAddress 按类型生成旧格式的服务器地址
+func dnsAddress(server config.DNSServer) (string, error) {
+	switch server.Type {
+	case "":
+		if server.Address == "" {
+			return "", fmt.Errorf("缺少地址")
+		}
+		return server.Address, nil
+	case "system":
+		return "local", nil
+	case "dhcp":
+		iface := strings.TrimPrefix(server.Address, "dhcp://")
+		if iface == "" {
+			iface = "auto"
+		}
+		return "dhcp://" + iface, nil
+	}
+
+	scheme, ok := dnsSchemes[server.Type]
+	if !ok {
+		return "", fmt.Errorf("类型无效: %s", server.Type)
+	}
+	if server.Address == "" {
+		return "", fmt.Errorf("缺少地址")
+	}
+
+	address := server.Address
+	if strings.Contains(address, "://") {
+		if !strings.HasPrefix(address, scheme) {
+			return "", fmt.Errorf("地址 %s 与类型 %s 不符", address, server.Type)
+		}
+	} else {
+		address = scheme + address
+	}
+
+	// DoH 未指定路径时使用标准路径
+	if server.Type == "https" || server.Type == "h3" {
+		if !strings.Contains(strings.TrimPrefix(address, scheme), "/") {
+			address += "/dns-query"
+		}
+	}
+	return address, nil
+}
+
+// dnsHost 取出地址中的主机部分，local、dhcp 等无需解析的地址返回空
+func dnsHost(address string) string {
+	switch {
+	case address == "local", address == "fakeip",
+		strings.HasPrefix(address, "dhcp://"), strings.HasPrefix(address, "rcode://"):
+		return ""
+	}
+
+	if _, rest, ok := strings.Cut(address, "://"); ok {
+		address = rest
+	}
+	address, _, _ = strings.Cut(address, "/")
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return strings.Trim(address, "[]")
+}
+
+// dnsRule 生成 DNS 规则，server 必须指向已定义的服务器
+func (r *Renderer) dnsRule(rule config.DNSRule, servers map[string]bool, refs ruleRefs) (map[string]interface{}, error) {
+	if rule.Server == "" {
+		return nil, fmt.Errorf("缺少服务器")
+	}
+	if !servers[rule.Server] {
+		return nil, fmt.Errorf("服务器不存在: %s", rule.Server)
+	}
+
+	for _, pattern := range rule.DomainRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("域名正则无效: %s", pattern)
+		}
+	}
+	for _, tag := range rule.RuleSet {
+		if !refs.ruleSets[tag] {
+			return nil, fmt.Errorf("规则集不存在: %s", tag)
+		}
+	}
+	for _, queryType := range rule.QueryType {
+		if n, err := strconv.Atoi(queryType); err == nil {
+			if n <= 0 || n > 65535 {
+				return nil, fmt.Errorf("查询类型无效: %s", queryType)
+			}
+			continue
+		}
+		if !dnsQueryTypes[strings.ToUpper(queryType)] {
+			return nil, fmt.Errorf("查询类型无效: %s", queryType)
+		}
+	}
+
+	entry := make(map[string]interface{})
+	for key, values := range map[string][]string{
+		"domain":         rule.Domain,
+		"domain_suffix":  rule.DomainSuffix,
+		"domain_keyword": rule.DomainKeyword,
+		"domain_regex":   rule.DomainRegex,
+		"rule_set":       rule.RuleSet,
+		"query_type":     rule.QueryType,
+	} {
+		if len(values) > 0 {
+			entry[key] = values
+		}
+	}
+	if len(entry) == 0 {
+		return nil, fmt.Errorf("没有匹配条件")
+	}
+
+	entry["server"] = rule.Server
+	if rule.DisableCache {
+		entry["disable_cache"] = true
+	}
+	if rule.ClientSubnet != "" {
+		if err := r.checkClientSubnet(rule.ClientSubnet); err != nil {
+			return nil, err
+		}
+		entry["client_subnet"] = rule.ClientSubnet
+	}
+	return entry, nil
+}
+
+// checkClientSubnet 校验 client_subnet，目标内核需要支持该字段
+func (r *Renderer) checkClientSubnet(value string) error {
+	if !r.atLeast(clientSubnetVersion) {
+		return fmt.Errorf("client_subnet 需要 sing-box %s 及以上", clientSubnetVersion)
+	}
+	return checkCIDR(value)
+}
+
+// checkStrategy 校验解析策略
+func checkStrategy(strategy string) error {
+	switch strategy {
+	case "prefer_ipv4", "prefer_ipv6", "ipv4_only", "ipv6_only":
+		return nil
+	default:
+		return fmt.Errorf("解析策略无效: %s", strategy)
+	}
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+func TestClientSubnetVersion(t *testing.T) {
+	server := config.DNSServer{Tag: "remote", Type: "udp", Address: "8.8.8.8", ClientSubnet: "114.114.114.0/24"}
+	rule := config.DNSRule{DomainSuffix: []string{"cn"}, Server: "remote", ClientSubnet: "114.114.114.0/24"}
+	tags := map[string]bool{"remote": true}
+
+	tests := []struct {
+		version string
+		ok      bool
+	}{
+		{"", false},
+		{"1.8.10", false},
+		{"1.9.0-rc.1", false},
+		{"1.9.0", true},
+		{"1.11.4", true},
+	}
+	for _, tt := range tests {
+		r := New(nil)
+		r.SetVersion(tt.version)
+
+		entry, err := r.dnsServer(server, tags, ruleRefs{})
+		if (err == nil) != tt.ok {
+			t.Errorf("dnsServer(%q) = %v，期望成功为 %v", tt.version, err, tt.ok)
+		}
+		if err == nil && entry["client_subnet"] != server.ClientSubnet {
+			t.Errorf("dnsServer(%q) 的 client_subnet 为 %v", tt.version, entry["client_subnet"])
+		}
+		if _, err := r.dnsRule(rule, tags, ruleRefs{}); (err == nil) != tt.ok {
+			t.Errorf("dnsRule(%q) = %v，期望成功为 %v", tt.version, err, tt.ok)
+		}
+	}
+
+	r := New(nil)
+	r.SetVersion("1.9.0")
+	bad := server
+	bad.ClientSubnet = "not-a-cidr"
+	if _, err := r.dnsServer(bad, tags, ruleRefs{}); err == nil {
+		t.Error("dnsServer 应当拒绝无效的 client_subnet")
+	}
+}
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"fmt"
+	"net/netip"
+	"path/filepath"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+const (
+	defaultFakeIPv4Range = "198.18.0.0/15"
+	defaultFakeIPv6Range = "fc00::/18"
+)
+
+// fakeIPExcludes 默认不使用 FakeIP 的域名后缀：局域网域名以及系统联网检测、时间同步等依赖真实地址的服务
+var fakeIPExcludes = []string{
+	"lan",
+	"local",
+	"localdomain",
+	"localhost",
+	"home.arpa",
+	"in-addr.arpa",
+	"ip6.arpa",
+	"msftconnecttest.com",
+	"msftncsi.com",
+	"connectivitycheck.gstatic.com",
+	"connectivitycheck.android.com",
+	"captive.apple.com",
+	"detectportal.firefox.com",
+	"nmcheck.gnome.org",
+	"network-test.debian.org",
+	"time.windows.com",
+	"time.apple.com",
+	"pool.ntp.org",
+	"stun.l.google.com",
+}
+
+// fakeIP 生成 FakeIP 地址池配置和 DNS 规则，排除的域名交给 fallback 服务器真实解析
+func fakeIP(cfg config.FakeIPConfig, fallback string, refs ruleRefs) (map[string]interface{}, []map[string]interface{}, error) {
+	inet4 := cfg.Inet4Range
+	if inet4 == "" {
+		inet4 = defaultFakeIPv4Range
+	}
+	inet6 := cfg.Inet6Range
+	if inet6 == "" {
+		inet6 = defaultFakeIPv6Range
+	}
+	for _, r := range []string{inet4, inet6} {
+		if _, err := netip.ParsePrefix(r); err != nil {
+			return nil, nil, fmt.Errorf("FakeIP 地址池无效: %s", r)
+		}
+	}
+	// 跳过已停用的规则集
+	var ruleSets []string
+	for _, tag := range cfg.RuleSet {
+		if refs.disabled[tag] {
+			continue
+		}
+		if !refs.ruleSets[tag] {
+			return nil, nil, fmt.Errorf("FakeIP 引用了不存在的规则集: %s", tag)
+		}
+		ruleSets = append(ruleSets, tag)
+	}
+
+	excludes := append(append([]string{}, fakeIPExcludes...), cfg.Exclude...)
+	rules := []map[string]interface{}{
+		{
+			"domain_suffix": excludes,
+			"server":        fallback,
+		},
+	}
+
+	fake := map[string]interface{}{
+		"query_type": []string{"A", "AAAA"},
+		"server":     dnsFakeIP,
+	}
+	if len(cfg.Domain) > 0 || len(cfg.DomainSuffix) > 0 || len(cfg.RuleSet) > 0 {
+		// 只按规则集分配且规则集均已停用时，不再为任何域名分配 FakeIP
+		if len(cfg.Domain) == 0 && len(cfg.DomainSuffix) == 0 && len(ruleSets) == 0 {
+			return fakeIPOptions(inet4, inet6), rules, nil
+		}
+		// 多个匹配字段之间为或关系
+		if len(cfg.Domain) > 0 {
+			fake["domain"] = cfg.Domain
+		}
+		if len(cfg.DomainSuffix) > 0 {
+			fake["domain_suffix"] = cfg.DomainSuffix
+		}
+		if len(ruleSets) > 0 {
+			fake["rule_set"] = ruleSets
+		}
+	}
+	rules = append(rules, fake)
+
+	return fakeIPOptions(inet4, inet6), rules, nil
+}
+
+// fakeIPOptions 生成 FakeIP 地址池配置
+func fakeIPOptions(inet4, inet6 string) map[string]interface{} {
+	return map[string]interface{}{
+		"enabled":     true,
+		"inet4_range": inet4,
+		"inet6_range": inet6,
+	}
+}
+
+// experimental 生成 experimental 配置，启用 FakeIP 时用 cache_file 持久化地址映射，
+// 重启后已下发给应用的虚假地址仍能还原为域名
+func (r *Renderer) experimental() map[string]interface{} {
+	if !r.cfg.Singbox.DNS.FakeIP.Enabled {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"cache_file": map[string]interface{}{
+			"enabled":      true,
+			"path":         filepath.Join(config.GetConfigDir(), "cache.db"),
+			"store_fakeip": true,
+		},
+	}
+}
@@ -0,0 +1,311 @@
+package render
+
+import (
+	"fmt"
+	"net/netip"
+	"runtime"
+	"time"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/core"
+)
+
+const (
+	defaultListen     = "127.0.0.1"
+	lanListen         = "::" // 允许局域网连接时监听全部地址
+	defaultMixedPort  = 7890
+	defaultTUNAddress = "172.19.0.1/30"
+
+	// tunAddressVersion 起 TUN 使用合并的 address/route_address 字段，旧字段在 1.12 移除
+	tunAddressVersion = "1.10.0"
+)
+
+// inbounds 生成入站配置，未配置时只提供本地 mixed 入站
+func (r *Renderer) inbounds() ([]map[string]interface{}, error) {
+	inbounds := r.inboundConfigs()
+
+	result := make([]map[string]interface{}, 0, len(inbounds))
+	tags := make(map[string]bool, len(inbounds))
+	for i, in := range inbounds {
+		if in.Disabled {
+			continue
+		}
+
+		tag := inboundTag(in, i)
+		if tags[tag] {
+			return nil, fmt.Errorf("入站标签重复: %s", tag)
+		}
+		tags[tag] = true
+
+		var inbound map[string]interface{}
+		var err error
+		if in.Type == "tun" {
+			inbound, err = r.tunInbound(tag, in.TUN)
+		} else {
+			inbound, err = listenInbound(tag, in)
+		}
+		if err == nil {
+			// TUN 收到的是 IP 连接，默认用嗅探到的域名替换目标地址以便按域名分流
+			err = sniffOptions(inbound, in, in.Type == "tun")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("入站 %s: %w", tag, err)
+		}
+		result = append(result, inbound)
+	}
+
+	return result, nil
+}
+
+// inboundRoutes 为指定了出站的入站生成路由规则
+func (r *Renderer) inboundRoutes(outbounds map[string]bool) ([]map[string]interface{}, error) {
+	var rules []map[string]interface{}
+	for i, in := range r.inboundConfigs() {
+		if in.Disabled || in.Outbound == "" {
+			continue
+		}
+
+		tag := inboundTag(in, i)
+		if !outbounds[in.Outbound] {
+			return nil, fmt.Errorf("入站 %s 的出站不存在: %s", tag, in.Outbound)
+		}
+		rules = append(rules, map[string]interface{}{
+			"inbound":  []string{tag},
+			"outbound": in.Outbound,
+		})
+	}
+	return rules, nil
+}
+
+// inboundConfigs 返回配置的入站，未配置时使用默认 mixed 入站
+func (r *Renderer) inboundConfigs() []config.InboundConfig {
+	if len(r.cfg.Singbox.Inbounds) == 0 {
+		return []config.InboundConfig{{Type: "mixed", Tag: "mixed-in"}}
+	}
+	return r.cfg.Singbox.Inbounds
+}
+
+// inboundTag 返回入站标签，未设置时按类型和序号生成
+func inboundTag(in config.InboundConfig, index int) string {
+	if in.Tag != "" {
+		return in.Tag
+	}
+	return fmt.Sprintf("%s-in-%d", in.Type, index)
+}
+
+// listenInbound 生成监听端口的代理入站
+func listenInbound(tag string, in config.InboundConfig) (map[string]interface{}, error) {
+	switch in.Type {
+	case "mixed", "socks", "http":
+	case "redirect":
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			return nil, fmt.Errorf("redirect 仅支持 Linux 和 macOS")
+		}
+	case "tproxy":
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("tproxy 仅支持 Linux")
+		}
+	default:
+		return nil, fmt.Errorf("类型无效: %s", in.Type)
+	}
+
+	listen := in.Listen
+	if listen == "" {
+		listen = defaultListen
+	}
+	if in.AllowLAN && config.IsLoopback(listen) {
+		listen = lanListen
+	}
+
+	port := in.ListenPort
+	if port == 0 && in.Type == "mixed" {
+		port = defaultMixedPort
+	}
+	if port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("端口无效: %d", port)
+	}
+
+	inbound := map[string]interface{}{
+		"type":        in.Type,
+		"tag":         tag,
+		"listen":      listen,
+		"listen_port": port,
+	}
+
+	if len(in.Users) > 0 {
+		if in.Type != "mixed" && in.Type != "socks" && in.Type != "http" {
+			return nil, fmt.Errorf("%s 入站不支持认证", in.Type)
+		}
+		users := make([]map[string]interface{}, 0, len(in.Users))
+		for _, user := range in.Users {
+			if user.Username == "" {
+				return nil, fmt.Errorf("用户名不能为空")
+			}
+			users = append(users, map[string]interface{}{
+				"username": user.Username,
+				"password": user.Password,
+			})
+		}
+		inbound["users"] = users
+	}
+
+	// 代理入站对本机以外开放时必须认证，避免成为开放代理
+	if err := in.CheckAuth(); err != nil {
+		return nil, err
+	}
+
+	if in.Network != "" {
+		if in.Type != "tproxy" {
+			return nil, fmt.Errorf("只有 tproxy 入站可以设置 network")
+		}
+		if in.Network != "tcp" && in.Network != "udp" {
+			return nil, fmt.Errorf("网络类型无效: %s", in.Network)
+		}
+		inbound["network"] = in.Network
+	}
+
+	return inbound, nil
+}
+
+// sniffOptions 写入嗅探选项，overrideDefault 为未配置时是否替换目标地址
+func sniffOptions(inbound map[string]interface{}, in config.InboundConfig, overrideDefault bool) error {
+	if in.Sniff != nil && !*in.Sniff {
+		return nil
+	}
+	inbound["sniff"] = true
+
+	override := overrideDefault
+	if in.SniffOverrideDestination != nil {
+		override = *in.SniffOverrideDestination
+	}
+	if override {
+		inbound["sniff_override_destination"] = true
+	}
+
+	if in.SniffTimeout != "" {
+		if d, err := time.ParseDuration(in.SniffTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("嗅探超时无效: %s", in.SniffTimeout)
+		}
+		inbound["sniff_timeout"] = in.SniffTimeout
+	}
+	return nil
+}
+
+// tunInbound 生成 TUN 入站，地址字段按目标内核版本选择新旧写法
+func (r *Renderer) tunInbound(tag string, opts *config.TUNConfig) (map[string]interface{}, error) {
+	if opts == nil {
+		opts = &config.TUNConfig{}
+	}
+
+	inbound := map[string]interface{}{
+		"type":         "tun",
+		"tag":          tag,
+		"auto_route":   true,
+		"strict_route": opts.StrictRoute == nil || *opts.StrictRoute,
+	}
+
+	switch opts.Stack {
+	case "":
+	case "system", "gvisor", "mixed":
+		inbound["stack"] = opts.Stack
+	default:
+		return nil, fmt.Errorf("协议栈无效: %s", opts.Stack)
+	}
+	if opts.MTU != 0 {
+		if opts.MTU < 576 || opts.MTU > 65535 {
+			return nil, fmt.Errorf("MTU 无效: %d", opts.MTU)
+		}
+		inbound["mtu"] = opts.MTU
+	}
+	if opts.InterfaceName != "" {
+		inbound["interface_name"] = opts.InterfaceName
+	}
+
+	if len(opts.IncludeUID) > 0 || len(opts.ExcludeUID) > 0 {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("按用户过滤仅支持 Linux")
+		}
+		if len(opts.IncludeUID) > 0 {
+			inbound["include_uid"] = opts.IncludeUID
+		}
+		if len(opts.ExcludeUID) > 0 {
+			inbound["exclude_uid"] = opts.ExcludeUID
+		}
+	}
+
+	modern := r.atLeast(tunAddressVersion)
+	if opts.AutoRedirect {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("auto_redirect 仅支持 Linux")
+		}
+		if !modern {
+			return nil, fmt.Errorf("auto_redirect 需要 sing-box %s 及以上", tunAddressVersion)
+		}
+		inbound["auto_redirect"] = true
+	}
+
+	inet4 := opts.Inet4Address
+	if inet4 == "" {
+		inet4 = defaultTUNAddress
+	}
+	addresses := []string{inet4}
+	if opts.Inet6Address != "" {
+		addresses = append(addresses, opts.Inet6Address)
+	}
+	for _, address := range addresses {
+		if _, err := netip.ParsePrefix(address); err != nil {
+			return nil, fmt.Errorf("地址无效: %s", address)
+		}
+	}
+	for _, cidr := range append(append([]string{}, opts.RouteAddress...), opts.RouteExcludeAddress...) {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return nil, fmt.Errorf("路由网段无效: %s", cidr)
+		}
+	}
+
+	if modern {
+		inbound["address"] = addresses
+		if len(opts.RouteAddress) > 0 {
+			inbound["route_address"] = opts.RouteAddress
+		}
+		if len(opts.RouteExcludeAddress) > 0 {
+			inbound["route_exclude_address"] = opts.RouteExcludeAddress
+		}
+		return inbound, nil
+	}
+
+	// 1.10 之前 IPv4 与 IPv6 分开配置
+	inbound["inet4_address"] = inet4
+	if opts.Inet6Address != "" {
+		inbound["inet6_address"] = opts.Inet6Address
+	}
+	splitByFamily(inbound, "inet4_route_address", "inet6_route_address", opts.RouteAddress)
+	splitByFamily(inbound, "inet4_route_exclude_address", "inet6_route_exclude_address", opts.RouteExcludeAddress)
+	return inbound, nil
+}
+
+// splitByFamily 按 IPv4/IPv6 拆分网段写入对应的旧字段，网段已校验过
+func splitByFamily(inbound map[string]interface{}, key4, key6 string, cidrs []string) {
+	var v4, v6 []string
+	for _, cidr := range cidrs {
+		if netip.MustParsePrefix(cidr).Addr().Is4() {
+			v4 = append(v4, cidr)
+		} else {
+			v6 = append(v6, cidr)
+		}
+	}
+	if len(v4) > 0 {
+		inbound[key4] = v4
+	}
+	if len(v6) > 0 {
+		inbound[key6] = v6
+	}
+}
+
+// atLeast 目标内核版本是否不低于 version，未知版本按最低支持版本处理
+func (r *Renderer) atLeast(version string) bool {
+	if r.version == "" {
+		return false
+	}
+	return core.CompareVersions(r.version, version) >= 0
+}
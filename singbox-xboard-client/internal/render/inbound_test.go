@@ -0,0 +1,39 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+func TestListenInboundLAN(t *testing.T) {
+	users := []config.InboundUser{{Username: "user", Password: "pass"}}
+
+	tests := []struct {
+		name   string
+		in     config.InboundConfig
+		listen string
+		ok     bool
+	}{
+		{"仅本机", config.InboundConfig{Type: "mixed"}, defaultListen, true},
+		{"局域网且已认证", config.InboundConfig{Type: "mixed", AllowLAN: true, Users: users}, lanListen, true},
+		{"局域网未认证", config.InboundConfig{Type: "mixed", AllowLAN: true}, "", false},
+		{"局域网 socks 未认证", config.InboundConfig{Type: "socks", ListenPort: 1080, AllowLAN: true}, "", false},
+		{"局域网用户没有密码", config.InboundConfig{Type: "http", ListenPort: 8080, AllowLAN: true, Users: []config.InboundUser{{Username: "user"}}}, "", false},
+		{"指定监听地址", config.InboundConfig{Type: "mixed", Listen: "192.168.1.2", AllowLAN: true, Users: users}, "192.168.1.2", true},
+		{"监听全部地址未认证", config.InboundConfig{Type: "mixed", Listen: "0.0.0.0"}, "", false},
+		{"监听局域网地址未认证", config.InboundConfig{Type: "socks", Listen: "192.168.1.2", ListenPort: 1080}, "", false},
+		{"监听全部地址且已认证", config.InboundConfig{Type: "http", Listen: "::", ListenPort: 8080, Users: users}, "::", true},
+		{"监听 IPv6 回环地址", config.InboundConfig{Type: "mixed", Listen: "::1"}, "::1", true},
+	}
+	for _, tt := range tests {
+		inbound, err := listenInbound("in", tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: listenInbound = %v，期望成功为 %v", tt.name, err, tt.ok)
+			continue
+		}
+		if err == nil && inbound["listen"] != tt.listen {
+			t.Errorf("%s: 监听地址为 %v，期望 %s", tt.name, inbound["listen"], tt.listen)
+		}
+	}
+}
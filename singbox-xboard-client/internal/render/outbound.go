@@ -0,0 +1,190 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// URLTestURL urltest 分组和节点健康检查使用的测速地址
+const URLTestURL = "http://www.gstatic.com/generate_204"
+
+// urltest 分组的测速参数
+const (
+	urlTestInterval  = "5m"
+	urlTestTolerance = 50
+)
+
+// outbounds 生成出站：配置中的基础出站、订阅节点以及节点分组，同时返回所有出站标签
+func (r *Renderer) outbounds(servers []xboard.Server) ([]map[string]interface{}, map[string]bool, error) {
+	var result []map[string]interface{}
+	tags := make(map[string]bool)
+
+	add := func(outbound map[string]interface{}) {
+		result = append(result, outbound)
+		tags[outbound["tag"].(string)] = true
+	}
+
+	for _, out := range r.baseOutbounds() {
+		add(out)
+	}
+
+	// 订阅节点，与其他节点或分组同名时追加序号避免标签冲突
+	used := r.reservedTags()
+	nodeTags := make([]string, 0, len(servers))
+	for _, server := range servers {
+		node := server.ConvertToSingboxNode()
+		tag := uniqueTag(server.Name, used)
+		used[tag] = true
+		node["tag"] = tag
+		add(node)
+		nodeTags = append(nodeTags, tag)
+	}
+
+	// 节点分组
+	groups := []string{TagDirect}
+	if len(nodeTags) > 0 {
+		add(map[string]interface{}{
+			"type":      "urltest",
+			"tag":       TagAuto,
+			"outbounds": r.urlTestNodes(nodeTags),
+			"url":       URLTestURL,
+			"interval":  urlTestInterval,
+			"tolerance": urlTestTolerance,
+		})
+		add(map[string]interface{}{
+			"type":      "selector",
+			"tag":       TagSelect,
+			"outbounds": append([]string{TagAuto}, nodeTags...),
+			"default":   TagAuto,
+		})
+		groups = []string{TagSelect, TagDirect}
+	}
+
+	// 配置中的分组，未指定成员时使用节点分组
+	hasProxy := false
+	for _, out := range r.cfg.Singbox.Outbounds {
+		if out.Type != "selector" && out.Type != "urltest" {
+			continue
+		}
+		if tags[out.Tag] {
+			return nil, nil, fmt.Errorf("出站标签重复: %s", out.Tag)
+		}
+
+		members := out.Outbounds
+		if len(members) == 0 {
+			members = groups
+		}
+		for _, member := range members {
+			if !tags[member] {
+				return nil, nil, fmt.Errorf("分组 %s 引用了不存在的出站: %s", out.Tag, member)
+			}
+		}
+
+		group := map[string]interface{}{
+			"type":      out.Type,
+			"tag":       out.Tag,
+			"outbounds": members,
+		}
+		if out.Type == "selector" {
+			group["default"] = members[0]
+			if out.Default != "" {
+				group["default"] = out.Default
+			}
+		} else {
+			group["url"] = URLTestURL
+			group["interval"] = urlTestInterval
+			group["tolerance"] = urlTestTolerance
+		}
+		add(group)
+		hasProxy = hasProxy || out.Tag == TagProxy
+	}
+
+	// 路由和 DNS 依赖 proxy 出站
+	if !hasProxy {
+		add(map[string]interface{}{
+			"type":      "selector",
+			"tag":       TagProxy,
+			"outbounds": groups,
+			"default":   groups[0],
+		})
+	}
+
+	return result, tags, nil
+}
+
+// urlTestNodes 去掉被排除的节点，全部被排除时保留所有节点
+func (r *Renderer) urlTestNodes(nodeTags []string) []string {
+	result := make([]string, 0, len(nodeTags))
+	for _, tag := range nodeTags {
+		if !r.excluded[tag] {
+			result = append(result, tag)
+		}
+	}
+	if len(result) == 0 {
+		return nodeTags
+	}
+	return result
+}
+
+// NodeTags 返回订阅节点在生成配置中的出站标签，顺序与 servers 一致
+func (r *Renderer) NodeTags(servers []xboard.Server) []string {
+	used := r.reservedTags()
+
+	tags := make([]string, 0, len(servers))
+	for _, server := range servers {
+		tag := uniqueTag(server.Name, used)
+		used[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// reservedTags 基础出站、内置分组和配置中的分组占用的标签，订阅节点不能使用
+func (r *Renderer) reservedTags() map[string]bool {
+	used := map[string]bool{TagAuto: true, TagSelect: true, TagProxy: true}
+	for _, out := range r.baseOutbounds() {
+		used[out["tag"].(string)] = true
+	}
+	for _, out := range r.cfg.Singbox.Outbounds {
+		if out.Type == "selector" || out.Type == "urltest" {
+			used[out.Tag] = true
+		}
+	}
+	return used
+}
+
+// baseOutbounds 配置中的基础出站，缺少的内置出站自动补齐
+func (r *Renderer) baseOutbounds() []map[string]interface{} {
+	var result []map[string]interface{}
+	tags := make(map[string]bool)
+	for _, out := range r.cfg.Singbox.Outbounds {
+		switch out.Type {
+		case "direct", "block", "dns":
+			result = append(result, map[string]interface{}{"type": out.Type, "tag": out.Tag})
+			tags[out.Tag] = true
+		}
+	}
+	for _, builtin := range []map[string]interface{}{
+		{"type": "direct", "tag": TagDirect},
+		{"type": "block", "tag": TagBlock},
+		{"type": "dns", "tag": TagDNS},
+	} {
+		if !tags[builtin["tag"].(string)] {
+			result = append(result, builtin)
+		}
+	}
+	return result
+}
+
+// uniqueTag 返回未被占用的标签
+func uniqueTag(name string, used map[string]bool) string {
+	if name == "" {
+		name = "node"
+	}
+	tag := name
+	for i := 2; used[tag]; i++ {
+		tag = fmt.Sprintf("%s (%d)", name, i)
+	}
+	return tag
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+func TestNodeTagsAvoidGroups(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Singbox.Outbounds = append(cfg.Singbox.Outbounds, config.OutboundConfig{Type: "selector", Tag: "streaming"})
+
+	var servers []xboard.Server
+	for _, name := range []string{"auto", "select", "proxy", "streaming", "direct", "hk", "hk"} {
+		servers = append(servers, xboard.Server{Name: name, Type: "shadowsocks"})
+	}
+
+	r := New(cfg)
+	outbounds, _, err := r.outbounds(servers)
+	if err != nil {
+		t.Fatalf("outbounds 失败: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, out := range outbounds {
+		tag := out["tag"].(string)
+		if seen[tag] {
+			t.Errorf("出站标签重复: %s", tag)
+		}
+		seen[tag] = true
+	}
+
+	want := []string{"auto (2)", "select (2)", "proxy (2)", "streaming (2)", "direct (2)", "hk", "hk (2)"}
+	got := r.NodeTags(servers)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("NodeTags = %v，期望 %v", got, want)
+			break
+		}
+		if !seen[want[i]] {
+			t.Errorf("生成的出站中没有节点 %s", want[i])
+		}
+	}
+}
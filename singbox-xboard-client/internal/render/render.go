@@ -0,0 +1,108 @@
+// Package render 根据应用配置和订阅节点生成 sing-box 配置
+package render
+
+import (
+	"fmt"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// 生成配置中固定使用的出站标签
+const (
+	TagDirect = "direct"  // 直连
+	TagBlock  = "block"   // 拦截
+	TagDNS    = "dns-out" // DNS 出站
+	TagProxy  = "proxy"   // 主代理选择器
+	TagSelect = "select"  // 手动选择节点
+	TagAuto   = "auto"    // 自动测速选择
+)
+
+// Renderer sing-box 配置生成器
+type Renderer struct {
+	cfg      *config.Config
+	version  string          // 目标内核版本，为空时生成兼容最低支持版本的配置
+	excluded map[string]bool // 不参与自动测速选择的节点
+}
+
+// New 创建配置生成器，cfg 为空时使用默认配置
+func New(cfg *config.Config) *Renderer {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	return &Renderer{cfg: cfg}
+}
+
+// SetVersion 设置目标内核版本，部分字段会按版本选择写法
+func (r *Renderer) SetVersion(version string) {
+	r.version = version
+}
+
+// SetExcluded 设置不参与自动测速选择的节点，手动选择时仍可使用
+func (r *Renderer) SetExcluded(tags map[string]bool) {
+	r.excluded = tags
+}
+
+// Render 将应用配置与订阅节点合并为完整的 sing-box 配置
+func (r *Renderer) Render(servers []xboard.Server) (map[string]interface{}, error) {
+	outbounds, tags, err := r.outbounds(servers)
+	if err != nil {
+		return nil, fmt.Errorf("生成出站失败: %w", err)
+	}
+
+	refs := ruleRefs{outbounds: tags, disabled: make(map[string]bool)}
+	for _, set := range r.cfg.Singbox.Route.RuleSet {
+		if set.Disabled {
+			refs.disabled[set.Tag] = true
+		}
+	}
+
+	ruleSets, err := r.ruleSets(tags, refs.disabled)
+	if err != nil {
+		return nil, fmt.Errorf("生成规则集失败: %w", err)
+	}
+	refs.ruleSets = make(map[string]bool, len(ruleSets))
+	for _, set := range ruleSets {
+		refs.ruleSets[set["tag"].(string)] = true
+	}
+
+	dns, err := r.dns(refs)
+	if err != nil {
+		return nil, fmt.Errorf("生成 DNS 失败: %w", err)
+	}
+
+	route, err := r.route(refs, ruleSets)
+	if err != nil {
+		return nil, fmt.Errorf("生成路由失败: %w", err)
+	}
+
+	inbounds, err := r.inbounds()
+	if err != nil {
+		return nil, fmt.Errorf("生成入站失败: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"log":       r.log(),
+		"dns":       dns,
+		"inbounds":  inbounds,
+		"outbounds": outbounds,
+		"route":     route,
+	}
+	if experimental := r.experimental(); experimental != nil {
+		result["experimental"] = experimental
+	}
+	return result, nil
+}
+
+// log 生成日志配置
+func (r *Renderer) log() map[string]interface{} {
+	level := r.cfg.LogLevel
+	if level == "" {
+		level = "info"
+	}
+
+	return map[string]interface{}{
+		"level":     level,
+		"timestamp": true,
+	}
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/ruleset"
+)
+
+// route 生成路由配置，用户规则优先于预设规则
+func (r *Renderer) route(refs ruleRefs, ruleSets []map[string]interface{}) (map[string]interface{}, error) {
+	cfg := r.cfg.Singbox.Route
+
+	// 固定出站的入站不受规则模式影响
+	inboundRules, err := r.inboundRoutes(refs.outbounds)
+	if err != nil {
+		return nil, err
+	}
+	rules := []map[string]interface{}{
+		{"protocol": "dns", "outbound": TagDNS},
+	}
+	rules = append(rules, inboundRules...)
+
+	// clash_mode 规则供 Clash API 在运行时切换全局和直连模式
+	rules = append(rules,
+		map[string]interface{}{"clash_mode": config.ModeDirect, "outbound": TagDirect},
+		map[string]interface{}{"clash_mode": config.ModeGlobal, "outbound": TagProxy},
+	)
+	for i, rule := range cfg.Rules {
+		if refs.usesDisabled(rule) {
+			continue
+		}
+		entry, err := refs.routeRule(rule, false)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条规则: %w", i+1, err)
+		}
+		rules = append(rules, entry)
+	}
+	rules = append(rules,
+		map[string]interface{}{
+			"ip_is_private": true,
+			"outbound":      TagDirect,
+		},
+		map[string]interface{}{
+			"rule_set": []string{ruleset.GeositeCN, ruleset.GeoIPCN},
+			"outbound": TagDirect,
+		},
+		map[string]interface{}{
+			"rule_set": []string{ruleset.GeositeNotCN},
+			"outbound": TagProxy,
+		},
+	)
+
+	final := cfg.Final
+	if final == "" {
+		final = TagProxy
+	}
+	if !refs.outbounds[final] {
+		return nil, fmt.Errorf("默认出站不存在: %s", final)
+	}
+
+	// Clash API 只接受规则中出现过的模式，以直连或全局模式启动后需要这条规则才能切回规则模式；
+	// 它位于最后，效果与默认出站相同
+	rules = append(rules, map[string]interface{}{
+		"clash_mode": config.ModeRule,
+		"outbound":   final,
+	})
+
+	result := map[string]interface{}{
+		"rules":                 rules,
+		"rule_set":              ruleSets,
+		"final":                 final,
+		"auto_detect_interface": true,
+	}
+
+	// 进程和用户规则依赖连接所属进程，开启后连接列表也会带上进程路径
+	findProcess := cfg.FindProcess
+	for _, rule := range cfg.Rules {
+		findProcess = findProcess || needsProcess(rule)
+	}
+	if findProcess {
+		result["find_process"] = true
+	}
+
+	return result, nil
+}
+
+// ruleSets 生成内置和配置中的规则集，已缓存的远程规则集引用本地文件，跳过已停用的规则集
+func (r *Renderer) ruleSets(outbounds, disabled map[string]bool) ([]map[string]interface{}, error) {
+	manager := ruleset.NewManager(r.cfg.Singbox.Route)
+	sets, err := manager.Sets()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(sets))
+	for _, set := range sets {
+		if disabled[set.Tag] && !set.Preset {
+			continue
+		}
+		set = manager.Resolve(set)
+		entry := map[string]interface{}{
+			"tag":    set.Tag,
+			"type":   set.Type,
+			"format": set.Format,
+		}
+
+		if set.Type == ruleset.TypeLocal {
+			entry["path"] = set.Path
+		} else {
+			entry["url"] = set.URL
+			entry["update_interval"] = set.UpdateInterval
+			if set.DownloadDetour != "" {
+				if !outbounds[set.DownloadDetour] {
+					return nil, fmt.Errorf("规则集 %s 的下载出站不存在: %s", set.Tag, set.DownloadDetour)
+				}
+				entry["download_detour"] = set.DownloadDetour
+			}
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
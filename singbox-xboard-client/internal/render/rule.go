@@ -0,0 +1,235 @@
+package render
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// ruleRefs 规则可引用的出站和规则集
+type ruleRefs struct {
+	outbounds map[string]bool
+	ruleSets  map[string]bool
+	disabled  map[string]bool // 已停用的规则集
+}
+
+// usesDisabled 规则或其子规则是否引用了已停用的规则集
+func (refs ruleRefs) usesDisabled(rule config.RouteRule) bool {
+	if refs.anyDisabled(rule.RuleSet) {
+		return true
+	}
+	for _, sub := range rule.Rules {
+		if refs.usesDisabled(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyDisabled 标签中是否有已停用的规则集
+func (refs ruleRefs) anyDisabled(tags []string) bool {
+	for _, tag := range tags {
+		if refs.disabled[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// routeRule 将配置中的路由规则转换为 sing-box 格式，nested 表示逻辑规则的子规则
+func (refs ruleRefs) routeRule(rule config.RouteRule, nested bool) (map[string]interface{}, error) {
+	var entry map[string]interface{}
+	var err error
+
+	switch rule.Type {
+	case "", "default":
+		entry, err = refs.defaultRule(rule)
+	case "logical":
+		entry, err = refs.logicalRule(rule)
+	default:
+		return nil, fmt.Errorf("规则类型无效: %s", rule.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Invert {
+		entry["invert"] = true
+	}
+
+	if nested {
+		if rule.Outbound != "" {
+			return nil, fmt.Errorf("子规则不能设置出站")
+		}
+		return entry, nil
+	}
+
+	if rule.Outbound == "" {
+		return nil, fmt.Errorf("缺少出站")
+	}
+	if !refs.outbounds[rule.Outbound] {
+		return nil, fmt.Errorf("出站不存在: %s", rule.Outbound)
+	}
+	entry["outbound"] = rule.Outbound
+	return entry, nil
+}
+
+// logicalRule 生成 and/or 逻辑规则
+func (refs ruleRefs) logicalRule(rule config.RouteRule) (map[string]interface{}, error) {
+	if rule.Mode != "and" && rule.Mode != "or" {
+		return nil, fmt.Errorf("逻辑规则模式无效: %s", rule.Mode)
+	}
+	if len(rule.Rules) == 0 {
+		return nil, fmt.Errorf("逻辑规则缺少子规则")
+	}
+
+	rules := make([]map[string]interface{}, 0, len(rule.Rules))
+	for i, sub := range rule.Rules {
+		entry, err := refs.routeRule(sub, true)
+		if err != nil {
+			return nil, fmt.Errorf("子规则 %d: %w", i+1, err)
+		}
+		rules = append(rules, entry)
+	}
+
+	return map[string]interface{}{
+		"type":  "logical",
+		"mode":  rule.Mode,
+		"rules": rules,
+	}, nil
+}
+
+// defaultRule 生成普通匹配规则
+func (refs ruleRefs) defaultRule(rule config.RouteRule) (map[string]interface{}, error) {
+	if rule.Mode != "" || len(rule.Rules) > 0 {
+		return nil, fmt.Errorf("只有逻辑规则可以设置模式和子规则")
+	}
+
+	for _, pattern := range rule.DomainRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("域名正则无效: %s", pattern)
+		}
+	}
+	for _, cidr := range append(append([]string{}, rule.IP...), rule.SourceIP...) {
+		if err := checkCIDR(cidr); err != nil {
+			return nil, err
+		}
+	}
+	for _, port := range rule.Port {
+		if port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("端口无效: %d", port)
+		}
+	}
+	for _, portRange := range rule.PortRange {
+		if err := checkPortRange(portRange); err != nil {
+			return nil, err
+		}
+	}
+	for _, network := range rule.Network {
+		if network != "tcp" && network != "udp" {
+			return nil, fmt.Errorf("网络类型无效: %s", network)
+		}
+	}
+	if (len(rule.User) > 0 || len(rule.UserID) > 0) && runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("按用户匹配仅支持 Linux")
+	}
+	for _, tag := range rule.RuleSet {
+		if !refs.ruleSets[tag] {
+			return nil, fmt.Errorf("规则集不存在: %s", tag)
+		}
+	}
+
+	entry := make(map[string]interface{})
+	for key, values := range map[string][]string{
+		"domain":         rule.Domain,
+		"domain_suffix":  rule.DomainSuffix,
+		"domain_keyword": rule.DomainKeyword,
+		"domain_regex":   rule.DomainRegex,
+		"ip_cidr":        rule.IP,
+		"source_ip_cidr": rule.SourceIP,
+		"port_range":     rule.PortRange,
+		"network":        rule.Network,
+		"protocol":       rule.Protocol,
+		"inbound":        rule.Inbound,
+		"process_name":   rule.ProcessName,
+		"process_path":   rule.ProcessPath,
+		"user":           rule.User,
+		"rule_set":       rule.RuleSet,
+	} {
+		if len(values) > 0 {
+			entry[key] = values
+		}
+	}
+	if len(rule.Port) > 0 {
+		entry["port"] = rule.Port
+	}
+	if len(rule.UserID) > 0 {
+		entry["user_id"] = rule.UserID
+	}
+
+	if len(entry) == 0 {
+		return nil, fmt.Errorf("没有匹配条件")
+	}
+	return entry, nil
+}
+
+// checkCIDR 校验 IP 或 CIDR
+func checkCIDR(value string) error {
+	if _, err := netip.ParsePrefix(value); err == nil {
+		return nil
+	}
+	if _, err := netip.ParseAddr(value); err == nil {
+		return nil
+	}
+	return fmt.Errorf("IP 或 CIDR 无效: %s", value)
+}
+
+// checkPortRange 校验 sing-box 端口范围格式：起始:结束，任一端可省略
+func checkPortRange(value string) error {
+	start, end, ok := strings.Cut(value, ":")
+	if !ok || (start == "" && end == "") {
+		return fmt.Errorf("端口范围无效: %s", value)
+	}
+
+	parse := func(s string, fallback int) (int, error) {
+		if s == "" {
+			return fallback, nil
+		}
+		port, err := strconv.Atoi(s)
+		if err != nil || port <= 0 || port > 65535 {
+			return 0, fmt.Errorf("端口范围无效: %s", value)
+		}
+		return port, nil
+	}
+
+	from, err := parse(start, 1)
+	if err != nil {
+		return err
+	}
+	to, err := parse(end, 65535)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("端口范围无效: %s", value)
+	}
+	return nil
+}
+
+// needsProcess 规则是否按进程或用户匹配，需要 sing-box 开启 find_process
+func needsProcess(rule config.RouteRule) bool {
+	if len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0 {
+		return true
+	}
+	for _, sub := range rule.Rules {
+		if needsProcess(sub) {
+			return true
+		}
+	}
+	return false
+}
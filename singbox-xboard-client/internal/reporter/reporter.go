@@ -0,0 +1,356 @@
+// Package reporter 定期向面板上报各节点流量，未送达的上报持久化在本地队列中重试
+package reporter
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+	"github.com/your-username/singbox-xboard-client/internal/traffic"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+
+	// 重试退避
+	initialBackoff = 30 * time.Second
+	maxBackoff     = 30 * time.Minute
+
+	// 队列上限，超出或过期的上报会被丢弃
+	maxQueueSize = 1000
+	maxReportAge = 7 * 24 * time.Hour
+)
+
+// Sender 上报目标，subscription.Manager 实现了该接口
+type Sender interface {
+	ReportTraffic(report xboard.TrafficReport) error
+	// NodeIDs 返回出站标签到节点 ID 的映射
+	NodeIDs() (map[string]int, error)
+}
+
+// Report 待发送的上报
+type Report struct {
+	xboard.TrafficReport
+	Node        string    `json:"node"`
+	CreatedAt   time.Time `json:"created_at"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Reporter 流量上报器
+type Reporter struct {
+	sender    Sender
+	interval  time.Duration
+	meter     *traffic.Meter
+	queuePath string
+	logger    *logrus.Logger
+
+	mu    sync.Mutex
+	queue []Report
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// New 创建上报器，并载入上次未发送的上报，队列保存在配置目录下的 report-queue.json
+func New(sender Sender, cfg config.SubscriptionConfig) *Reporter {
+	interval := time.Duration(cfg.ReportInterval) * time.Minute
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	r := &Reporter{
+		sender:    sender,
+		interval:  interval,
+		meter:     traffic.NewMeter(),
+		queuePath: filepath.Join(config.GetConfigDir(), "report-queue.json"),
+		logger:    logrus.New(),
+	}
+	if err := r.load(); err != nil {
+		r.logger.Warnf("读取流量上报队列失败: %v", err)
+	}
+	return r
+}
+
+// SetLogger 设置日志记录器
+func (r *Reporter) SetLogger(logger *logrus.Logger) {
+	r.logger = logger
+}
+
+// Record 累计一次连接变化中的流量
+func (r *Reporter) Record(update singbox.ConnectionsUpdate) {
+	r.meter.Record(update)
+}
+
+// Pending 返回尚未送达的上报
+func (r *Reporter) Pending() []Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Report{}, r.queue...)
+}
+
+// Start 开始定期上报
+func (r *Reporter) Start() {
+	r.stopCh = make(chan struct{})
+	r.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(r.doneCh)
+
+		// 先补发上次遗留的上报
+		r.send()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.enqueue()
+				r.send()
+			case <-r.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止上报，尚未发送的流量写入队列，下次启动时补发
+func (r *Reporter) Stop() {
+	if r.stopCh != nil {
+		close(r.stopCh)
+		<-r.doneCh
+		r.stopCh = nil
+	}
+	r.enqueue()
+}
+
+// Flush 立即汇总并发送
+func (r *Reporter) Flush() {
+	r.enqueue()
+	r.send()
+}
+
+// enqueue 将累计的增量按节点生成上报并落盘，非订阅节点（如直连）的流量不上报
+func (r *Reporter) enqueue() {
+	deltas := r.meter.Take()
+	if len(deltas) == 0 {
+		return
+	}
+
+	ids, err := r.sender.NodeIDs()
+	if err != nil {
+		r.logger.Warnf("获取节点列表失败，稍后上报: %v", err)
+		r.meter.Restore(deltas)
+		return
+	}
+
+	now := time.Now()
+	var reports []Report
+	for node, delta := range deltas {
+		id, ok := ids[node]
+		if !ok {
+			continue
+		}
+		reportID, err := newReportID()
+		if err != nil {
+			r.logger.Warnf("生成上报编号失败: %v", err)
+			r.meter.Restore(map[string]traffic.Counter{node: delta})
+			continue
+		}
+		reports = append(reports, Report{
+			TrafficReport: xboard.TrafficReport{
+				ReportID:  reportID,
+				NodeID:    id,
+				Upload:    delta.Upload,
+				Download:  delta.Download,
+				Timestamp: now.Unix(),
+			},
+			Node:        node,
+			CreatedAt:   now,
+			NextAttempt: now,
+		})
+	}
+	if len(reports) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	r.queue = append(r.queue, reports...)
+	if dropped := len(r.queue) - maxQueueSize; dropped > 0 {
+		r.logger.Warnf("流量上报队列已满，丢弃最早的 %d 条", dropped)
+		r.queue = r.queue[dropped:]
+	}
+	err = r.save()
+	r.mu.Unlock()
+	if err != nil {
+		r.logger.Warnf("保存流量上报队列失败: %v", err)
+	}
+}
+
+// send 按顺序发送到期的上报，成功或无法重试的上报移出队列。
+// 遇到面板不可达等非 HTTP 状态错误时停止本轮发送，剩余上报一起推迟
+func (r *Reporter) send() {
+	r.mu.Lock()
+	queue := append([]Report{}, r.queue...)
+	r.mu.Unlock()
+
+	now := time.Now()
+	done := make(map[string]bool)
+	retry := make(map[string]Report)
+	var deferUntil time.Time // 非零时本轮不再发送，剩余上报推迟到该时间
+	for _, report := range queue {
+		if now.Sub(report.CreatedAt) > maxReportAge {
+			r.logger.Warnf("流量上报 %s 超过 %s 仍未送达，已丢弃", report.ReportID, maxReportAge)
+			done[report.ReportID] = true
+			continue
+		}
+		if !deferUntil.IsZero() {
+			if report.NextAttempt.Before(deferUntil) {
+				report.NextAttempt = deferUntil
+				retry[report.ReportID] = report
+			}
+			continue
+		}
+		if now.Before(report.NextAttempt) {
+			continue
+		}
+
+		err := r.sender.ReportTraffic(report.TrafficReport)
+		if err == nil || isDuplicate(err) {
+			done[report.ReportID] = true
+			continue
+		}
+		if isPermanent(err) {
+			r.logger.Errorf("面板拒绝流量上报 %s，已丢弃: %v", report.ReportID, err)
+			done[report.ReportID] = true
+			continue
+		}
+
+		report.Attempts++
+		report.NextAttempt = now.Add(backoff(report.Attempts))
+		report.LastError = err.Error()
+		retry[report.ReportID] = report
+		r.logger.Warnf("流量上报失败，%s 后重试: %v", report.NextAttempt.Sub(now).Round(time.Second), err)
+
+		// 没有收到面板响应，继续发送其余上报也会失败
+		var status *xboard.StatusError
+		if !errors.As(err, &status) {
+			deferUntil = report.NextAttempt
+		}
+	}
+	if len(done) == 0 && len(retry) == 0 {
+		return
+	}
+
+	// 发送期间可能有新的上报入队，按编号合并结果
+	r.mu.Lock()
+	queue = r.queue[:0]
+	for _, report := range r.queue {
+		if done[report.ReportID] {
+			continue
+		}
+		if updated, ok := retry[report.ReportID]; ok {
+			report = updated
+		}
+		queue = append(queue, report)
+	}
+	r.queue = queue
+	err := r.save()
+	r.mu.Unlock()
+	if err != nil {
+		r.logger.Warnf("保存流量上报队列失败: %v", err)
+	}
+}
+
+// load 读取持久化的队列
+func (r *Reporter) load() error {
+	data, err := os.ReadFile(r.queuePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &r.queue)
+}
+
+// save 原子写入队列，调用方需持有 r.mu
+func (r *Reporter) save() error {
+	if len(r.queue) == 0 {
+		if err := os.Remove(r.queuePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.queue, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.queuePath), 0755); err != nil {
+		return err
+	}
+
+	tmp := r.queuePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, r.queuePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// backoff 第 attempts 次失败后的等待时长
+func backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// isDuplicate 面板已经记录过该编号
+func isDuplicate(err error) bool {
+	var status *xboard.StatusError
+	return errors.As(err, &status) && status.Code == http.StatusConflict
+}
+
+// isPermanent 重试也不会成功的错误，如请求被拒绝
+func isPermanent(err error) bool {
+	var status *xboard.StatusError
+	if !errors.As(err, &status) {
+		return false
+	}
+	switch status.Code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+		return false
+	}
+	return status.Code >= 400 && status.Code < 500
+}
+
+// newReportID 生成随机上报编号
+func newReportID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("读取随机数失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
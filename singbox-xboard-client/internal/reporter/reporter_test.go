@@ -0,0 +1,132 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// fakeSender 按节点 ID 返回预设的错误并记录发送顺序
+type fakeSender struct {
+	errs map[int]error
+	sent []int
+}
+
+func (f *fakeSender) ReportTraffic(report xboard.TrafficReport) error {
+	f.sent = append(f.sent, report.NodeID)
+	return f.errs[report.NodeID]
+}
+
+func (f *fakeSender) NodeIDs() (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+func newTestReporter(t *testing.T, sender Sender, nodes ...int) *Reporter {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	r := New(sender, config.SubscriptionConfig{})
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	r.SetLogger(logger)
+
+	now := time.Now()
+	for _, node := range nodes {
+		r.queue = append(r.queue, Report{
+			TrafficReport: xboard.TrafficReport{ReportID: fmt.Sprint(node), NodeID: node, Upload: 1},
+			CreatedAt:     now,
+			NextAttempt:   now,
+		})
+	}
+	return r
+}
+
+func statusError(code int) error {
+	return &xboard.StatusError{Code: code, Status: http.StatusText(code)}
+}
+
+func TestSendStopsOnTransportError(t *testing.T) {
+	sender := &fakeSender{errs: map[int]error{2: errors.New("connection refused")}}
+	r := newTestReporter(t, sender, 1, 2, 3, 4)
+
+	before := time.Now()
+	r.send()
+
+	if fmt.Sprint(sender.sent) != "[1 2]" {
+		t.Fatalf("发送顺序为 %v，期望在节点 2 失败后停止", sender.sent)
+	}
+	pending := r.Pending()
+	if len(pending) != 3 {
+		t.Fatalf("队列剩余 %d 条，期望 3", len(pending))
+	}
+	for _, report := range pending {
+		if report.NextAttempt.Before(before.Add(initialBackoff)) {
+			t.Errorf("上报 %s 的下次发送时间为 %v，期望整体推迟", report.ReportID, report.NextAttempt)
+		}
+	}
+	if pending[0].Attempts != 1 || pending[1].Attempts != 0 || pending[2].Attempts != 0 {
+		t.Errorf("尝试次数为 %d、%d、%d，期望只有失败的上报计数", pending[0].Attempts, pending[1].Attempts, pending[2].Attempts)
+	}
+
+	// 推迟期间不再发送
+	r.send()
+	if len(sender.sent) != 2 {
+		t.Errorf("推迟期间又发送了 %v", sender.sent[2:])
+	}
+}
+
+func TestSendContinuesOnStatusError(t *testing.T) {
+	sender := &fakeSender{errs: map[int]error{
+		1: statusError(http.StatusServiceUnavailable), // 可重试
+		2: statusError(http.StatusConflict),           // 已记录过
+		3: statusError(http.StatusBadRequest),         // 被拒绝
+	}}
+	r := newTestReporter(t, sender, 1, 2, 3, 4)
+
+	r.send()
+
+	if fmt.Sprint(sender.sent) != "[1 2 3 4]" {
+		t.Fatalf("发送顺序为 %v，期望全部发送", sender.sent)
+	}
+	pending := r.Pending()
+	if len(pending) != 1 || pending[0].NodeID != 1 || pending[0].Attempts != 1 || pending[0].LastError == "" {
+		t.Errorf("队列为 %+v，期望只保留可重试的节点 1", pending)
+	}
+}
+
+func TestSendDropsExpired(t *testing.T) {
+	sender := &fakeSender{}
+	r := newTestReporter(t, sender, 1, 2)
+	r.queue[0].CreatedAt = time.Now().Add(-maxReportAge - time.Hour)
+
+	r.send()
+
+	if fmt.Sprint(sender.sent) != "[2]" || len(r.Pending()) != 0 {
+		t.Errorf("发送了 %v，队列剩余 %d 条，期望丢弃过期上报并发送其余上报", sender.sent, len(r.Pending()))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, initialBackoff},
+		{2, 2 * initialBackoff},
+		{3, 4 * initialBackoff},
+		{10, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v，期望 %v", tt.attempts, got, tt.want)
+		}
+	}
+}
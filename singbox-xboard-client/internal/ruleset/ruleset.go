@@ -0,0 +1,386 @@
+// Package ruleset 管理 sing-box 规则集及其本地缓存
+package ruleset
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// DefaultMirror 内置规则集的默认下载地址模板
+const DefaultMirror = "https://raw.githubusercontent.com/SagerNet/sing-{kind}/rule-set/{tag}.srs"
+
+// 内置规则集标签
+const (
+	GeositeCN    = "geosite-cn"
+	GeositeNotCN = "geosite-geolocation-!cn"
+	GeoIPCN      = "geoip-cn"
+)
+
+// 规则集类型和格式
+const (
+	TypeLocal    = "local"
+	TypeRemote   = "remote"
+	FormatBinary = "binary"
+	FormatSource = "source"
+)
+
+const defaultUpdateInterval = "1d"
+
+// presets 内置规则集：标签到数据来源（geosite 或 geoip）的映射，按生成顺序排列
+var presets = []struct {
+	tag  string
+	kind string
+}{
+	{GeositeCN, "geosite"},
+	{GeositeNotCN, "geosite"},
+	{GeoIPCN, "geoip"},
+}
+
+// Set 规则集定义
+type Set struct {
+	Tag            string `json:"tag"`
+	Type           string `json:"type"`
+	Format         string `json:"format"`
+	Path           string `json:"path,omitempty"`
+	URL            string `json:"url,omitempty"`
+	DownloadDetour string `json:"download_detour,omitempty"`
+	UpdateInterval string `json:"update_interval,omitempty"`
+	Preset         bool   `json:"preset"`
+}
+
+// Info 规则集及其缓存状态
+type Info struct {
+	Set
+	Cached    bool      `json:"cached"`
+	CachePath string    `json:"cache_path,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	Age       float64   `json:"age,omitempty"` // 距上次下载的秒数
+	Size      int64     `json:"size,omitempty"`
+	Stale     bool      `json:"stale"`
+}
+
+// Manager 规则集管理器
+type Manager struct {
+	dir        string
+	route      config.RouteConfig
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewManager 创建规则集管理器，缓存目录为配置目录下的 rule-sets
+func NewManager(route config.RouteConfig) *Manager {
+	return &Manager{
+		dir:        filepath.Join(config.GetConfigDir(), "rule-sets"),
+		route:      route,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		logger:     logrus.New(),
+	}
+}
+
+// Sets 返回内置规则集和配置中的规则集
+func (m *Manager) Sets() ([]Set, error) {
+	mirror := m.route.Presets.Mirror
+	if mirror == "" {
+		mirror = DefaultMirror
+	}
+	interval := m.route.Presets.UpdateInterval
+	if interval == "" {
+		interval = defaultUpdateInterval
+	}
+
+	sets := make([]Set, 0, len(presets)+len(m.route.RuleSet))
+	tags := make(map[string]bool)
+	for _, preset := range presets {
+		sets = append(sets, Set{
+			Tag:            preset.tag,
+			Type:           TypeRemote,
+			Format:         FormatBinary,
+			URL:            strings.NewReplacer("{kind}", preset.kind, "{tag}", preset.tag).Replace(mirror),
+			DownloadDetour: m.route.Presets.DownloadDetour,
+			UpdateInterval: interval,
+			Preset:         true,
+		})
+		tags[preset.tag] = true
+	}
+
+	for _, rs := range m.route.RuleSet {
+		set, err := fromConfig(rs)
+		if err != nil {
+			return nil, err
+		}
+		if tags[set.Tag] {
+			return nil, fmt.Errorf("规则集标签重复: %s", set.Tag)
+		}
+		tags[set.Tag] = true
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+// fromConfig 校验配置中的规则集并补全类型和格式
+func fromConfig(rs config.RuleSet) (Set, error) {
+	set := Set{
+		Tag:            rs.Tag,
+		Type:           rs.Type,
+		Format:         rs.Format,
+		Path:           rs.Path,
+		URL:            rs.URL,
+		DownloadDetour: rs.DownloadDetour,
+		UpdateInterval: rs.UpdateInterval,
+	}
+	if set.Tag == "" {
+		return set, fmt.Errorf("规则集缺少标签")
+	}
+
+	if set.Type == "" {
+		set.Type = TypeLocal
+		if set.URL != "" {
+			set.Type = TypeRemote
+		}
+	}
+	switch set.Type {
+	case TypeLocal:
+		if set.Path == "" {
+			return set, fmt.Errorf("本地规则集 %s 缺少路径", set.Tag)
+		}
+	case TypeRemote:
+		if set.URL == "" {
+			return set, fmt.Errorf("远程规则集 %s 缺少地址", set.Tag)
+		}
+		if set.UpdateInterval == "" {
+			set.UpdateInterval = defaultUpdateInterval
+		}
+		if _, err := parseInterval(set.UpdateInterval); err != nil {
+			return set, fmt.Errorf("规则集 %s: %w", set.Tag, err)
+		}
+	default:
+		return set, fmt.Errorf("规则集 %s 类型无效: %s", set.Tag, set.Type)
+	}
+
+	if set.Format == "" {
+		set.Format = FormatBinary
+		if strings.HasSuffix(set.Path+set.URL, ".json") {
+			set.Format = FormatSource
+		}
+	}
+	if set.Format != FormatBinary && set.Format != FormatSource {
+		return set, fmt.Errorf("规则集 %s 格式无效: %s", set.Tag, set.Format)
+	}
+
+	return set, nil
+}
+
+// Resolve 已缓存的远程规则集改为引用本地文件，避免 sing-box 启动时重复下载
+func (m *Manager) Resolve(set Set) Set {
+	if set.Type != TypeRemote {
+		return set
+	}
+
+	path := m.cachePath(set)
+	if _, err := os.Stat(path); err != nil {
+		return set
+	}
+
+	return Set{
+		Tag:    set.Tag,
+		Type:   TypeLocal,
+		Format: set.Format,
+		Path:   path,
+		Preset: set.Preset,
+	}
+}
+
+// List 列出所有规则集及其缓存状态
+func (m *Manager) List() ([]Info, error) {
+	sets, err := m.Sets()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(sets))
+	for _, set := range sets {
+		infos = append(infos, m.info(set))
+	}
+	return infos, nil
+}
+
+// info 读取规则集的缓存状态，本地规则集按源文件计算
+func (m *Manager) info(set Set) Info {
+	info := Info{Set: set}
+
+	path := set.Path
+	if set.Type == TypeRemote {
+		path = m.cachePath(set)
+		info.CachePath = path
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		info.Stale = set.Type == TypeRemote
+		return info
+	}
+
+	info.Cached = true
+	info.UpdatedAt = stat.ModTime()
+	info.Age = time.Since(info.UpdatedAt).Seconds()
+	info.Size = stat.Size()
+	if set.Type == TypeRemote {
+		interval, _ := parseInterval(set.UpdateInterval)
+		info.Stale = time.Since(info.UpdatedAt) >= interval
+	}
+	return info
+}
+
+// Refresh 下载指定的远程规则集，未指定时下载全部
+func (m *Manager) Refresh(tags ...string) error {
+	sets, err := m.Sets()
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		wanted[tag] = true
+	}
+
+	var failed []string
+	for _, set := range sets {
+		if len(tags) > 0 && !wanted[set.Tag] {
+			continue
+		}
+		delete(wanted, set.Tag)
+
+		if set.Type != TypeRemote {
+			continue
+		}
+		if err := m.download(set); err != nil {
+			m.logger.Warnf("下载规则集 %s 失败: %v", set.Tag, err)
+			failed = append(failed, set.Tag)
+		}
+	}
+
+	for tag := range wanted {
+		return fmt.Errorf("规则集不存在: %s", tag)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("以下规则集下载失败: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RefreshStale 下载未缓存或已超过更新间隔的远程规则集，返回成功更新的数量
+func (m *Manager) RefreshStale() (int, error) {
+	infos, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+
+	var tags []string
+	for _, info := range infos {
+		if info.Stale {
+			tags = append(tags, info.Tag)
+		}
+	}
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	err = m.Refresh(tags...)
+	if err == nil {
+		return len(tags), nil
+	}
+
+	// 部分下载失败时统计已更新的规则集
+	refreshed := 0
+	if infos, listErr := m.List(); listErr == nil {
+		stale := make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			stale[tag] = true
+		}
+		for _, info := range infos {
+			if stale[info.Tag] && !info.Stale {
+				refreshed++
+			}
+		}
+	}
+	return refreshed, err
+}
+
+// download 下载规则集到缓存目录，完成后再替换旧文件
+func (m *Manager) download(set Set) error {
+	m.logger.Infof("下载规则集 %s: %s", set.Tag, set.URL)
+
+	resp, err := m.httpClient.Get(set.URL)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("服务器返回错误: %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	file, err := os.CreateTemp(m.dir, "download-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("写入失败: %w", err)
+	}
+
+	if err := os.Rename(file.Name(), m.cachePath(set)); err != nil {
+		return fmt.Errorf("保存规则集失败: %w", err)
+	}
+	return nil
+}
+
+// cachePath 返回远程规则集的缓存文件路径
+func (m *Manager) cachePath(set Set) string {
+	ext := ".srs"
+	if set.Format == FormatSource {
+		ext = ".json"
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(set.Tag)
+	return filepath.Join(m.dir, name+ext)
+}
+
+// parseInterval 解析更新间隔，在 time.ParseDuration 基础上支持以天为单位
+func parseInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("更新间隔无效: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("更新间隔无效: %s", s)
+	}
+	return d, nil
+}
+
+// SetLogger 设置日志记录器
+func (m *Manager) SetLogger(logger *logrus.Logger) {
+	m.logger = logger
+}
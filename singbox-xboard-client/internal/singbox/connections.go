@@ -0,0 +1,410 @@
+package singbox
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/clashapi"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+const (
+	// defaultClashController 未配置时 Clash API 的监听地址
+	defaultClashController = "127.0.0.1:9091"
+
+	// connectionPollInterval 连接轮询间隔
+	connectionPollInterval = time.Second
+)
+
+// Connection 经过代理的连接
+type Connection struct {
+	ID          string    `json:"id"`
+	Network     string    `json:"network"`                // tcp, udp
+	Inbound     string    `json:"inbound"`                // 入站，如 mixed/mixed-in
+	Source      string    `json:"source"`                 // 来源地址
+	Host        string    `json:"host,omitempty"`         // 目标域名
+	Destination string    `json:"destination"`            // 目标地址
+	Rule        string    `json:"rule"`                   // 命中的规则
+	RulePayload string    `json:"rule_payload,omitempty"` // 规则内容
+	Outbound    string    `json:"outbound"`               // 实际使用的出站
+	Chains      []string  `json:"chains"`                 // 出站链
+	ProcessPath string    `json:"process_path,omitempty"` // 发起连接的进程
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+}
+
+// newConnection 从 Clash API 的连接转换
+func newConnection(c clashapi.Connection) Connection {
+	conn := Connection{
+		ID:          c.ID,
+		Network:     c.Metadata.Network,
+		Inbound:     c.Metadata.Type,
+		Source:      joinHostPort(c.Metadata.SourceIP, c.Metadata.SourcePort),
+		Host:        c.Metadata.Host,
+		Destination: joinHostPort(c.Metadata.DestinationIP, c.Metadata.DestinationPort),
+		Rule:        c.Rule,
+		RulePayload: c.RulePayload,
+		Chains:      c.Chains,
+		ProcessPath: c.Metadata.ProcessPath,
+		Upload:      c.Upload,
+		Download:    c.Download,
+		Start:       c.Start,
+	}
+	if len(c.Chains) > 0 {
+		conn.Outbound = c.Chains[0]
+	}
+	return conn
+}
+
+// joinHostPort 拼接地址，缺少部分时原样返回
+func joinHostPort(host, port string) string {
+	if host == "" || port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// ConnectionsUpdate 一次轮询得到的连接变化。
+//
+// 连接列表每秒轮询一次，连接在上次轮询后产生的流量以及两次轮询之间开始并结束的连接都不在快照中。
+// 为使各出站的流量之和与 sing-box 的累计流量一致，本次累计流量的增量中没有出现在任何连接上的部分
+// 按比例计入本次关闭的连接；没有连接关闭时无法判断归属，计入 UnattributedUpload/UnattributedDownload
+type ConnectionsUpdate struct {
+	Added                []Connection `json:"added,omitempty"`
+	Updated              []Connection `json:"updated,omitempty"`
+	Closed               []Connection `json:"closed,omitempty"` // 已关闭的连接及其最终流量
+	UploadTotal          int64        `json:"upload_total"`
+	DownloadTotal        int64        `json:"download_total"`
+	Time                 time.Time    `json:"time"` // 取得快照的时间，按相邻两次的累计流量和时间计算速率
+	UnattributedUpload   int64        `json:"unattributed_upload,omitempty"`
+	UnattributedDownload int64        `json:"unattributed_download,omitempty"`
+}
+
+// Empty 是否没有任何变化
+func (u *ConnectionsUpdate) Empty() bool {
+	return len(u.Added) == 0 && len(u.Updated) == 0 && len(u.Closed) == 0 &&
+		u.UnattributedUpload == 0 && u.UnattributedDownload == 0
+}
+
+// connectionTracker 轮询 Clash API 并计算连接变化
+type connectionTracker struct {
+	client   *clashapi.Client
+	logger   *logrus.Logger
+	onUpdate func(ConnectionsUpdate)
+
+	mu     sync.RWMutex
+	active map[string]Connection
+	upload int64
+	down   int64
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// newConnectionTracker 创建连接跟踪器
+func newConnectionTracker(client *clashapi.Client, logger *logrus.Logger, onUpdate func(ConnectionsUpdate)) *connectionTracker {
+	return &connectionTracker{
+		client:   client,
+		logger:   logger,
+		onUpdate: onUpdate,
+		active:   make(map[string]Connection),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// run 周期性轮询，直到 stop 被调用
+func (t *connectionTracker) run() {
+	defer close(t.doneCh)
+
+	ticker := time.NewTicker(connectionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.poll()
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// poll 拉取一次连接列表并通知变化
+func (t *connectionTracker) poll() {
+	snapshot, err := t.client.GetConnections()
+	if err != nil {
+		// 进程刚启动时 API 可能尚未就绪
+		t.logger.Debugf("获取连接失败: %v", err)
+		return
+	}
+
+	update := ConnectionsUpdate{
+		UploadTotal:   snapshot.UploadTotal,
+		DownloadTotal: snapshot.DownloadTotal,
+		Time:          time.Now(),
+	}
+
+	t.mu.Lock()
+	// 快照中各连接的流量增量
+	var observedUp, observedDown int64
+	seen := make(map[string]struct{}, len(snapshot.Connections))
+	for _, c := range snapshot.Connections {
+		conn := newConnection(c)
+		seen[conn.ID] = struct{}{}
+
+		previous, ok := t.active[conn.ID]
+		switch {
+		case !ok:
+			update.Added = append(update.Added, conn)
+		case previous.Upload != conn.Upload || previous.Download != conn.Download:
+			update.Updated = append(update.Updated, conn)
+		}
+		observedUp += max(conn.Upload-previous.Upload, 0)
+		observedDown += max(conn.Download-previous.Download, 0)
+		t.active[conn.ID] = conn
+	}
+	for id, conn := range t.active {
+		if _, ok := seen[id]; !ok {
+			update.Closed = append(update.Closed, conn)
+			delete(t.active, id)
+		}
+	}
+
+	// 累计流量变小说明内核已重新加载，从 0 开始计算
+	previousUp, previousDown := t.upload, t.down
+	if snapshot.UploadTotal < previousUp || snapshot.DownloadTotal < previousDown {
+		previousUp, previousDown = 0, 0
+	}
+	t.upload = snapshot.UploadTotal
+	t.down = snapshot.DownloadTotal
+	t.mu.Unlock()
+
+	update.reconcile(
+		max(snapshot.UploadTotal-previousUp-observedUp, 0),
+		max(snapshot.DownloadTotal-previousDown-observedDown, 0),
+	)
+
+	t.onUpdate(update)
+}
+
+// reconcile 将连接快照之外的流量按各连接已有的流量比例计入本次关闭的连接，
+// 没有关闭的连接时计入未归属流量
+func (u *ConnectionsUpdate) reconcile(upload, download int64) {
+	if len(u.Closed) == 0 {
+		u.UnattributedUpload = upload
+		u.UnattributedDownload = download
+		return
+	}
+
+	ups := distribute(upload, len(u.Closed), func(i int) int64 { return u.Closed[i].Upload })
+	downs := distribute(download, len(u.Closed), func(i int) int64 { return u.Closed[i].Download })
+	for i := range u.Closed {
+		u.Closed[i].Upload += ups[i]
+		u.Closed[i].Download += downs[i]
+	}
+}
+
+// distribute 按权重把 amount 分成 n 份，权重都为 0 时平均分配，舍入的余数计入最后一份
+func distribute(amount int64, n int, weight func(int) int64) []int64 {
+	shares := make([]int64, n)
+	if amount <= 0 || n == 0 {
+		return shares
+	}
+
+	var total int64
+	for i := 0; i < n; i++ {
+		total += weight(i)
+	}
+	var assigned int64
+	for i := 0; i < n-1; i++ {
+		if total > 0 {
+			shares[i] = int64(float64(amount) * float64(weight(i)) / float64(total))
+		} else {
+			shares[i] = amount / int64(n)
+		}
+		assigned += shares[i]
+	}
+	shares[n-1] = amount - assigned
+	return shares
+}
+
+// stop 停止轮询，进程退出后剩余连接均视为已关闭
+func (t *connectionTracker) stop() {
+	if t == nil {
+		return
+	}
+	close(t.stopCh)
+	<-t.doneCh
+
+	t.mu.Lock()
+	update := ConnectionsUpdate{
+		UploadTotal:   t.upload,
+		DownloadTotal: t.down,
+		Time:          time.Now(),
+	}
+	for id, conn := range t.active {
+		update.Closed = append(update.Closed, conn)
+		delete(t.active, id)
+	}
+	t.mu.Unlock()
+
+	if !update.Empty() {
+		t.onUpdate(update)
+	}
+}
+
+// OnConnections 注册连接变化钩子
+func (m *Manager) OnConnections(hook func(ConnectionsUpdate)) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+	m.connHooks = append(m.connHooks, hook)
+}
+
+// handleConnections 更新流量统计并通知连接变化钩子
+func (m *Manager) handleConnections(update ConnectionsUpdate) {
+	m.statsTracker.mu.Lock()
+	m.statsTracker.upload = update.UploadTotal
+	m.statsTracker.download = update.DownloadTotal
+	m.statsTracker.lastUpdate = time.Now()
+	m.statsTracker.mu.Unlock()
+
+	m.processes.record(update.Added)
+
+	m.hookMu.RLock()
+	hooks := make([]func(ConnectionsUpdate), len(m.connHooks))
+	copy(hooks, m.connHooks)
+	m.hookMu.RUnlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					m.logger.Errorf("连接钩子执行失败: %v", r)
+				}
+			}()
+			hook(update)
+		}()
+	}
+}
+
+// ClashAPI 返回运行中实例的 Clash API 客户端
+func (m *Manager) ClashAPI() (*clashapi.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.isRunning || m.clash == nil {
+		return nil, fmt.Errorf("sing-box 未运行")
+	}
+	return m.clash, nil
+}
+
+// Connections 获取当前活动连接
+func (m *Manager) Connections() ([]Connection, error) {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := client.GetConnections()
+	if err != nil {
+		return nil, err
+	}
+
+	conns := make([]Connection, 0, len(snapshot.Connections))
+	for _, c := range snapshot.Connections {
+		conns = append(conns, newConnection(c))
+	}
+	return conns, nil
+}
+
+// CloseConnection 关闭指定连接
+func (m *Manager) CloseConnection(id string) error {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return err
+	}
+	return client.CloseConnection(id)
+}
+
+// CloseAllConnections 关闭所有连接
+func (m *Manager) CloseAllConnections() error {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return err
+	}
+	return client.CloseAllConnections()
+}
+
+// SelectOutbound 在运行中实例的 selector 出站组中选择出站
+func (m *Manager) SelectOutbound(group, outbound string) error {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return err
+	}
+	if err := client.SelectProxy(group, outbound); err != nil {
+		return fmt.Errorf("切换 %s 失败: %w", group, err)
+	}
+
+	m.logger.Infof("%s 已切换为 %s", group, outbound)
+	return nil
+}
+
+// SelectedOutbound 返回运行中实例的 selector 出站组当前选择的出站
+func (m *Manager) SelectedOutbound(group string) (string, error) {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return "", err
+	}
+	proxies, err := client.GetProxies()
+	if err != nil {
+		return "", err
+	}
+	proxy, ok := proxies[group]
+	if !ok {
+		return "", fmt.Errorf("出站组不存在: %s", group)
+	}
+	return proxy.Now, nil
+}
+
+// TestDelay 通过指定出站访问 testURL，返回延迟（毫秒）
+func (m *Manager) TestDelay(outbound, testURL string, timeout time.Duration) (int, error) {
+	client, err := m.ClashAPI()
+	if err != nil {
+		return 0, err
+	}
+	return client.GetDelay(outbound, testURL, timeout)
+}
+
+// clashAPIConfig 返回 Clash API 的监听地址和密钥
+func clashAPIConfig(cfg *config.Config) (controller, secret string) {
+	controller = cfg.Singbox.ClashAPI.ExternalController
+	if controller == "" {
+		controller = defaultClashController
+	}
+	return controller, cfg.Singbox.ClashAPI.Secret
+}
+
+// applyClashAPI 在 sing-box 配置中启用 Clash API
+func applyClashAPI(singboxConfig map[string]interface{}, cfg *config.Config) {
+	controller, secret := clashAPIConfig(cfg)
+
+	experimental, _ := singboxConfig["experimental"].(map[string]interface{})
+	if experimental == nil {
+		experimental = make(map[string]interface{})
+		singboxConfig["experimental"] = experimental
+	}
+
+	clashAPI, _ := experimental["clash_api"].(map[string]interface{})
+	if clashAPI == nil {
+		clashAPI = make(map[string]interface{})
+		experimental["clash_api"] = clashAPI
+	}
+	clashAPI["external_controller"] = controller
+	clashAPI["secret"] = secret
+	clashAPI["default_mode"] = configMode(cfg)
+}
@@ -0,0 +1,54 @@
+package singbox
+
+import "testing"
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name             string
+		closed           []Connection
+		upload, download int64
+		wantUp           []int64
+		wantDown         []int64
+		wantUnattributed [2]int64
+	}{
+		{
+			name:             "没有关闭的连接",
+			upload:           100,
+			download:         200,
+			wantUnattributed: [2]int64{100, 200},
+		},
+		{
+			name:     "按已有流量比例分配",
+			closed:   []Connection{{Upload: 10, Download: 300}, {Upload: 30, Download: 100}},
+			upload:   40,
+			download: 400,
+			wantUp:   []int64{20, 60},
+			wantDown: []int64{600, 200},
+		},
+		{
+			name:     "没有流量时平均分配，余数计入最后一个",
+			closed:   []Connection{{}, {}, {}},
+			upload:   10,
+			download: 0,
+			wantUp:   []int64{3, 3, 4},
+			wantDown: []int64{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := ConnectionsUpdate{Closed: tt.closed}
+			update.reconcile(tt.upload, tt.download)
+
+			for i, conn := range update.Closed {
+				if conn.Upload != tt.wantUp[i] || conn.Download != tt.wantDown[i] {
+					t.Errorf("连接 %d 的流量为 %d/%d，期望 %d/%d", i, conn.Upload, conn.Download, tt.wantUp[i], tt.wantDown[i])
+				}
+			}
+			got := [2]int64{update.UnattributedUpload, update.UnattributedDownload}
+			if got != tt.wantUnattributed {
+				t.Errorf("未归属流量为 %v，期望 %v", got, tt.wantUnattributed)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package singbox
+
+import "time"
+
+// EventType 生命周期事件类型
+type EventType string
+
+const (
+	EventStarted    EventType = "started"    // 进程已启动
+	EventStopped    EventType = "stopped"    // 进程被手动停止
+	EventReloaded   EventType = "reloaded"   // 配置已热重载
+	EventExited     EventType = "exited"     // 进程意外退出
+	EventRestarting EventType = "restarting" // 即将自动重启
+	EventCrashLoop  EventType = "crash_loop" // 连续快速失败，放弃重启
+)
+
+// Event sing-box 生命周期事件
+type Event struct {
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Exit    *ExitInfo `json:"exit,omitempty"`    // 退出信息
+	Attempt int       `json:"attempt,omitempty"` // 第几次自动重启
+	Delay   float64   `json:"delay,omitempty"`   // 重启前等待时长（秒）
+}
+
+// OnEvent 注册生命周期事件钩子
+func (m *Manager) OnEvent(hook func(Event)) {
+	m.hookMu.Lock()
+	defer m.hookMu.Unlock()
+	m.eventHooks = append(m.eventHooks, hook)
+}
+
+// emit 按顺序通知所有事件钩子，调用方不能持有 m.mu
+func (m *Manager) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	m.hookMu.RLock()
+	hooks := make([]func(Event), len(m.eventHooks))
+	copy(hooks, m.eventHooks)
+	m.hookMu.RUnlock()
+
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					m.logger.Errorf("事件钩子执行失败: %v", r)
+				}
+			}()
+			hook(event)
+		}()
+	}
+}
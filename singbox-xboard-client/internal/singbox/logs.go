@@ -0,0 +1,220 @@
+package singbox
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLogBufferSize 日志缓冲默认保留条数
+const defaultLogBufferSize = 1000
+
+// 日志级别，数值越大越严重
+var logLevels = map[string]int{
+	"trace": 0,
+	"debug": 1,
+	"info":  2,
+	"warn":  3,
+	"error": 4,
+	"fatal": 5,
+	"panic": 6,
+}
+
+var (
+	// ansiPattern 匹配终端颜色控制符
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// logLinePattern 匹配 sing-box 日志行，例如：
+	// +0800 2024-01-02 15:04:05 INFO [3782498851 12ms] inbound/mixed[mixed-in]: inbound connection to example.com:443
+	// FATAL[0000] start service: ...
+	logLinePattern = regexp.MustCompile(`^(?:([+-]\d{4} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}) )?(TRACE|DEBUG|INFO|WARN|ERROR|FATAL|PANIC)(?:\[\d+\])? (?:\[(\d+)(?: [^\]]*)?\] )?(?:([a-z][\w-]*(?:/[\w-]+)?(?:\[[^\]]*\])?): )?(.*)$`)
+)
+
+// LogEntry 结构化日志条目
+type LogEntry struct {
+	Time         time.Time `json:"time"`
+	Level        string    `json:"level"`
+	Component    string    `json:"component,omitempty"` // 组件，如 inbound/mixed[mixed-in]
+	Message      string    `json:"message"`
+	ConnectionID string    `json:"connection_id,omitempty"` // 连接 ID
+}
+
+// LogFilter 日志过滤条件
+type LogFilter struct {
+	Level   string // 最低级别
+	Keyword string // 包含的文本，不区分大小写
+	Limit   int    // 返回的最大条数，0 表示不限制
+}
+
+// Match 判断日志条目是否满足过滤条件
+func (f LogFilter) Match(entry LogEntry) bool {
+	if f.Level != "" {
+		if min, ok := logLevels[strings.ToLower(f.Level)]; ok && logLevels[entry.Level] < min {
+			return false
+		}
+	}
+
+	if f.Keyword != "" {
+		keyword := strings.ToLower(f.Keyword)
+		if !strings.Contains(strings.ToLower(entry.Message), keyword) &&
+			!strings.Contains(strings.ToLower(entry.Component), keyword) &&
+			entry.ConnectionID != f.Keyword {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseLogLine 解析一行 sing-box 日志，无法识别的行按 info 级别整体作为消息
+func ParseLogLine(line string) LogEntry {
+	line = strings.TrimSpace(ansiPattern.ReplaceAllString(line, ""))
+
+	match := logLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return LogEntry{
+			Time:    time.Now(),
+			Level:   "info",
+			Message: line,
+		}
+	}
+
+	entry := LogEntry{
+		Time:         time.Now(),
+		Level:        strings.ToLower(match[2]),
+		ConnectionID: match[3],
+		Component:    match[4],
+		Message:      match[5],
+	}
+
+	if match[1] != "" {
+		if t, err := time.Parse("-0700 2006-01-02 15:04:05", match[1]); err == nil {
+			entry.Time = t
+		}
+	}
+
+	return entry
+}
+
+// LogBuffer 有界的 sing-box 日志环形缓冲
+type LogBuffer struct {
+	mu          sync.RWMutex
+	entries     []LogEntry
+	start       int
+	size        int
+	subscribers map[chan LogEntry]struct{}
+}
+
+// NewLogBuffer 创建日志缓冲
+func NewLogBuffer(size int) *LogBuffer {
+	if size <= 0 {
+		size = defaultLogBufferSize
+	}
+	return &LogBuffer{
+		entries:     make([]LogEntry, 0, size),
+		size:        size,
+		subscribers: make(map[chan LogEntry]struct{}),
+	}
+}
+
+// Writer 返回按行解析 sing-box 输出的写入器，每个输出流应使用独立的写入器
+func (b *LogBuffer) Writer() io.Writer {
+	return &logWriter{buffer: b}
+}
+
+// logWriter 将单个输出流解析为日志条目
+type logWriter struct {
+	mu      sync.Mutex
+	buffer  *LogBuffer
+	partial string
+}
+
+// Write 实现 io.Writer
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = splitLines(w.partial, p, func(line string) {
+		w.buffer.Add(ParseLogLine(line))
+	})
+	return len(p), nil
+}
+
+// Add 追加一条日志并推送给订阅者
+func (b *LogBuffer) Add(entry LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) < b.size {
+		b.entries = append(b.entries, entry)
+	} else {
+		b.entries[b.start] = entry
+		b.start = (b.start + 1) % b.size
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- entry:
+		default:
+			// 订阅者消费过慢时丢弃
+		}
+	}
+}
+
+// Query 按时间顺序返回满足条件的日志，有数量限制时返回最新的若干条
+func (b *LogBuffer) Query(filter LogFilter) []LogEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := make([]LogEntry, 0)
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		entry := b.entries[(b.start+i)%len(b.entries)]
+		if !filter.Match(entry) {
+			continue
+		}
+		result = append(result, entry)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+
+	// 反转为时间正序
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// Subscribe 订阅新日志，返回的函数用于取消订阅
+func (b *LogBuffer) Subscribe() (<-chan LogEntry, func()) {
+	ch := make(chan LogEntry, 128)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// splitLines 将上次残留的内容与 p 拼接后逐行回调，返回新的残留内容
+func splitLines(partial string, p []byte, fn func(line string)) string {
+	parts := strings.Split(partial+string(p), "\n")
+	for _, line := range parts[:len(parts)-1] {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		fn(line)
+	}
+	return parts[len(parts)-1]
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,7 +12,10 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/clashapi"
 	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/core"
+	"github.com/your-username/singbox-xboard-client/internal/render"
 )
 
 // Manager sing-box 管理器
@@ -22,9 +26,35 @@ type Manager struct {
 	cancel       context.CancelFunc
 	logger       *logrus.Logger
 	mu           sync.Mutex
+	startMu      sync.Mutex // 串行化启动，查找内核和校验配置期间不持有 mu
+	updateMu     sync.Mutex // 串行化配置更新和重启，订阅更新钩子可能并发调用
 	isRunning    bool
 	configPath   string
 	statsTracker *StatsTracker
+
+	// 进程守护
+	supervisor   *supervisor
+	done         chan struct{} // 当前进程退出后关闭
+	stderrTail   *tailBuffer
+	restartTimer *time.Timer
+	crashLoop    bool
+	lastExit     *ExitInfo
+
+	logs          *LogBuffer
+	coreVersion   string
+	appliedConfig []byte // 运行中进程使用的配置
+
+	// Clash API 与连接跟踪
+	clash     *clashapi.Client
+	tracker   *connectionTracker
+	processes *processRecorder
+
+	hookMu     sync.RWMutex
+	eventHooks []func(Event)
+	connHooks  []func(ConnectionsUpdate)
+
+	// 启动前按实际内核版本生成配置，未设置时使用缓存的配置
+	renderer func(version string) (map[string]interface{}, error)
 }
 
 // StatsTracker 流量统计跟踪器
@@ -42,50 +72,124 @@ func NewManager(cfg *config.Config) *Manager {
 		config:       cfg,
 		logger:       logrus.New(),
 		statsTracker: &StatsTracker{},
+		supervisor:   newSupervisor(cfg.Singbox.Supervisor),
+		logs:         NewLogBuffer(defaultLogBufferSize),
+		processes:    newProcessRecorder(maxRecentProcesses),
 	}
 }
 
 // Start 启动 sing-box
 func (m *Manager) Start() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
 
+	m.mu.Lock()
 	if m.isRunning {
+		m.mu.Unlock()
 		return fmt.Errorf("sing-box 已在运行")
 	}
 
-	// 创建上下文
-	m.ctx, m.cancel = context.WithCancel(context.Background())
+	// 手动启动时取消待执行的自动重启并清空失败计数
+	m.cancelRestart()
+	m.supervisor.reset()
+	m.crashLoop = false
+	m.mu.Unlock()
+
+	plan, err := m.prepareStart()
+	if err != nil {
+		return err
+	}
 
-	// 准备配置文件
-	if err := m.prepareConfig(); err != nil {
-		return fmt.Errorf("准备配置失败: %w", err)
+	m.mu.Lock()
+	err = m.launch(plan)
+	m.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
+	m.emit(Event{Type: EventStarted, Message: "sing-box 已启动"})
+	return nil
+}
+
+// startPlan 启动前准备好的内核和配置
+type startPlan struct {
+	binary  string
+	version string
+	cfg     *config.Config
+	path    string
+	data    []byte
+}
+
+// prepareStart 查找内核、探测版本并生成和校验配置。这些步骤会运行子进程，
+// 调用方需持有 m.startMu，不能持有 m.mu
+func (m *Manager) prepareStart() (*startPlan, error) {
+	m.mu.Lock()
+	cfg := m.config
+	renderer := m.renderer
+	m.mu.Unlock()
+
 	// 查找 sing-box 可执行文件
-	singboxPath, err := m.findSingboxBinary()
+	binary, err := findSingboxBinary(cfg)
 	if err != nil {
-		return fmt.Errorf("找不到 sing-box: %w", err)
+		return nil, fmt.Errorf("找不到 sing-box: %w", err)
 	}
 
-	// 创建命令
-	m.process = exec.CommandContext(m.ctx, singboxPath, "run", "-c", m.configPath)
-	
-	// 设置输出
-	m.process.Stdout = m.logger.Writer()
-	m.process.Stderr = m.logger.Writer()
+	// 检查内核版本是否与生成的配置兼容
+	version, err := core.ProbeVersion(binary)
+	if err != nil {
+		return nil, err
+	}
+	if err := core.CheckCompatibility(version); err != nil {
+		return nil, err
+	}
+
+	// 准备配置文件
+	path, data, err := m.prepareConfig(cfg, version, renderer)
+	if err != nil {
+		return nil, fmt.Errorf("准备配置失败: %w", err)
+	}
+
+	return &startPlan{binary: binary, version: version, cfg: cfg, path: path, data: data}, nil
+}
+
+// launch 按准备好的内核和配置启动 sing-box 进程，调用方需持有 m.mu
+func (m *Manager) launch(plan *startPlan) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	process := exec.CommandContext(ctx, plan.binary, "run", "-c", plan.path)
+
+	// 设置输出，同时写入结构化日志缓冲，stderr 额外保留最后几行用于退出诊断
+	stderrTail := newTailBuffer(stderrTailLines)
+	output := m.logger.Writer()
+	process.Stdout = io.MultiWriter(output, m.logs.Writer())
+	process.Stderr = io.MultiWriter(output, m.logs.Writer(), stderrTail)
 
 	// 启动进程
-	if err := m.process.Start(); err != nil {
+	if err := process.Start(); err != nil {
+		cancel()
+		output.Close()
 		return fmt.Errorf("启动 sing-box 失败: %w", err)
 	}
 
+	m.ctx, m.cancel = ctx, cancel
+	m.coreVersion = plan.version
+	m.configPath = plan.path
+	m.stderrTail = stderrTail
+	m.process = process
+	m.done = make(chan struct{})
 	m.isRunning = true
 	m.statsTracker.startTime = time.Now()
 	m.statsTracker.lastUpdate = time.Now()
 
+	// 记录进程实际使用的配置，用于判断后续变更能否热重载
+	m.appliedConfig = plan.data
+
+	// 通过 Clash API 跟踪连接
+	m.clash = clashapi.NewClient(clashAPIConfig(plan.cfg))
+	m.tracker = newConnectionTracker(m.clash, m.logger, m.handleConnections)
+	go m.tracker.run()
+
 	// 监控进程
-	go m.monitorProcess()
+	go m.monitorProcess(process, output, m.done, m.stderrTail, m.statsTracker.startTime)
 
 	m.logger.Info("sing-box 已启动")
 	return nil
@@ -94,44 +198,67 @@ func (m *Manager) Start() error {
 // Stop 停止 sing-box
 func (m *Manager) Stop() error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+
+	// 正在等待自动重启时，直接取消重启
+	if !m.isRunning && m.cancelRestart() {
+		m.mu.Unlock()
+		m.logger.Info("已取消 sing-box 自动重启")
+		m.emit(Event{Type: EventStopped, Message: "已取消自动重启"})
+		return nil
+	}
 
 	if !m.isRunning {
+		m.mu.Unlock()
 		return fmt.Errorf("sing-box 未运行")
 	}
 
-	// 取消上下文
-	if m.cancel != nil {
-		m.cancel()
+	process := m.process
+	done := m.done
+
+	// 先请求进程优雅退出，超时后强制终止
+	if err := process.Process.Signal(os.Interrupt); err != nil {
+		process.Process.Kill()
 	}
 
-	// 等待进程退出
-	if m.process != nil {
-		// 给进程一些时间优雅退出
-		done := make(chan error, 1)
-		go func() {
-			done <- m.process.Wait()
-		}()
-
-		select {
-		case <-done:
-			// 进程已退出
-		case <-time.After(5 * time.Second):
-			// 强制终止
-			if err := m.process.Process.Kill(); err != nil {
-				m.logger.Warnf("强制终止失败: %v", err)
-			}
+	select {
+	case <-done:
+		// 进程已退出
+	case <-time.After(5 * time.Second):
+		// 强制终止
+		if err := process.Process.Kill(); err != nil {
+			m.logger.Warnf("强制终止失败: %v", err)
 		}
+		<-done
+	}
+
+	// 取消上下文
+	if m.cancel != nil {
+		m.cancel()
 	}
 
+	// 清空进程引用，monitorProcess 据此识别为手动停止
+	m.process = nil
 	m.isRunning = false
+	tracker := m.detachTracker()
+	m.mu.Unlock()
+
+	tracker.stop()
+
 	m.logger.Info("sing-box 已停止")
+	m.emit(Event{Type: EventStopped, Message: "sing-box 已停止"})
 	return nil
 }
 
 // Restart 重启 sing-box
 func (m *Manager) Restart() error {
-	if m.isRunning {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	return m.restart()
+}
+
+// restart 重启 sing-box，调用方需持有 m.updateMu
+func (m *Manager) restart() error {
+	if m.IsRunning() {
 		if err := m.Stop(); err != nil {
 			return fmt.Errorf("停止失败: %w", err)
 		}
@@ -148,25 +275,45 @@ func (m *Manager) IsRunning() bool {
 	return m.isRunning
 }
 
-// UpdateConfig 更新配置
+// UpdateConfig 更新配置，运行中时优先热重载，入站等变更才完整重启
 func (m *Manager) UpdateConfig(singboxConfig map[string]interface{}) error {
-	// 保存新配置
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	// 校验通过后保存新配置，这里是 singbox.json 唯一的写入方
+	cfg := m.appConfig()
+	applyClashAPI(singboxConfig, cfg)
 	configPath := filepath.Join(config.GetConfigDir(), "singbox.json")
 	data, err := json.MarshalIndent(singboxConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化配置失败: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("写入配置文件失败: %w", err)
+	if err := m.writeConfig(configPath, data, cfg); err != nil {
+		return err
 	}
 
-	// 如果正在运行，重启以应用新配置
-	if m.IsRunning() {
-		m.logger.Info("重启 sing-box 以应用新配置")
-		return m.Restart()
+	if !m.IsRunning() {
+		return nil
+	}
+
+	m.mu.Lock()
+	reason := restartReason(m.appliedConfig, data)
+	m.mu.Unlock()
+
+	if reason != "" {
+		m.logger.Infof("%s，完整重启 sing-box 以应用新配置", reason)
+		return m.restart()
 	}
 
+	if err := m.Reload(); err != nil {
+		m.logger.Warnf("热重载失败，改为完整重启: %v", err)
+		return m.restart()
+	}
+
+	m.mu.Lock()
+	m.appliedConfig = data
+	m.mu.Unlock()
 	return nil
 }
 
@@ -193,35 +340,88 @@ func (m *Manager) UpdateStats(upload, download int64) {
 	m.statsTracker.lastUpdate = time.Now()
 }
 
-// prepareConfig 准备配置文件
-func (m *Manager) prepareConfig() error {
+// prepareConfig 生成、校验并写入本次启动使用的配置，返回配置文件路径和内容
+func (m *Manager) prepareConfig(cfg *config.Config, version string, renderer func(string) (map[string]interface{}, error)) (string, []byte, error) {
 	// 获取配置目录
 	configDir := config.GetConfigDir()
 	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("创建配置目录失败: %w", err)
+		return "", nil, fmt.Errorf("创建配置目录失败: %w", err)
 	}
 
-	// 检查是否有缓存的 sing-box 配置
-	m.configPath = filepath.Join(configDir, "singbox.json")
-	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
-		// 创建默认配置
-		defaultConfig := m.createDefaultConfig()
-		data, err := json.MarshalIndent(defaultConfig, "", "  ")
-		if err != nil {
-			return fmt.Errorf("序列化默认配置失败: %w", err)
+	path := filepath.Join(configDir, "singbox.json")
+	var singboxConfig map[string]interface{}
+	var err error
+	if renderer != nil {
+		// 缓存的配置可能是按其他内核版本生成的，按本次启动的版本重新生成
+		if singboxConfig, err = renderer(version); err != nil {
+			return "", nil, fmt.Errorf("按 sing-box %s 生成配置失败: %w", version, err)
 		}
+	} else if singboxConfig, err = cachedConfig(path, cfg, version); err != nil {
+		return "", nil, err
+	}
 
-		if err := os.WriteFile(m.configPath, data, 0644); err != nil {
-			return fmt.Errorf("写入默认配置失败: %w", err)
-		}
+	// 确保启用 Clash API
+	applyClashAPI(singboxConfig, cfg)
+	data, err := json.MarshalIndent(singboxConfig, "", "  ")
+	if err != nil {
+		return "", nil, fmt.Errorf("序列化配置失败: %w", err)
 	}
 
-	return nil
+	if err := m.writeConfig(path, data, cfg); err != nil {
+		return "", nil, err
+	}
+	return path, data, nil
+}
+
+// cachedConfig 读取缓存的 sing-box 配置，不存在时创建默认配置
+func cachedConfig(path string, cfg *config.Config, version string) (map[string]interface{}, error) {
+	var singboxConfig map[string]interface{}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return createDefaultConfig(cfg, version)
+	case err != nil:
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &singboxConfig); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+	return singboxConfig, nil
+}
+
+// Binary 返回启动时将使用的 sing-box 可执行文件
+func (m *Manager) Binary() (string, error) {
+	return findSingboxBinary(m.appConfig())
+}
+
+// DetectVersion 返回启动时将使用的内核版本，用于在启动前按版本生成配置
+func (m *Manager) DetectVersion() (string, error) {
+	binary, err := findSingboxBinary(m.appConfig())
+	if err != nil {
+		return "", err
+	}
+	return core.ProbeVersion(binary)
 }
 
 // findSingboxBinary 查找 sing-box 可执行文件
-func (m *Manager) findSingboxBinary() (string, error) {
-	// 优先查找系统路径
+func findSingboxBinary(cfg *config.Config) (string, error) {
+	cores := core.NewManager(cfg.Core)
+
+	// 固定了版本时只使用内核管理器安装的版本
+	if cfg.Core.Version != "" {
+		return cores.BinaryPath(cfg.Core.Version)
+	}
+
+	// 其次使用内核管理器安装的最新兼容版本
+	if installed, err := cores.Installed(); err == nil {
+		for _, c := range installed {
+			if core.CheckCompatibility(c.Version) == nil {
+				return c.Path, nil
+			}
+		}
+	}
+
+	// 查找系统路径
 	if path, err := exec.LookPath("sing-box"); err == nil {
 		return path, nil
 	}
@@ -252,82 +452,218 @@ func (m *Manager) findSingboxBinary() (string, error) {
 	return "", fmt.Errorf("找不到 sing-box 可执行文件")
 }
 
-// monitorProcess 监控进程
-func (m *Manager) monitorProcess() {
-	err := m.process.Wait()
-	
+// monitorProcess 监控进程，意外退出时按重启策略处理
+func (m *Manager) monitorProcess(process *exec.Cmd, output io.Closer, done chan struct{}, stderr *tailBuffer, startedAt time.Time) {
+	err := process.Wait()
+	output.Close()
+	close(done)
+
 	m.mu.Lock()
+	if m.process != process {
+		// 手动停止，由 Stop 处理
+		m.mu.Unlock()
+		return
+	}
+
+	m.process = nil
 	m.isRunning = false
+	if m.cancel != nil {
+		m.cancel()
+	}
+	tracker := m.detachTracker()
+
+	exit := newExitInfo(err, time.Since(startedAt), stderr.Lines())
+	events := m.handleExit(exit)
 	m.mu.Unlock()
 
-	if err != nil {
-		m.logger.Errorf("sing-box 进程退出: %v", err)
+	tracker.stop()
+
+	for _, event := range events {
+		m.emit(event)
+	}
+}
+
+// handleExit 记录退出信息并安排重启，调用方需持有 m.mu
+func (m *Manager) handleExit(exit *ExitInfo) []Event {
+	m.lastExit = exit
+	if exit.Failed() {
+		m.logger.Errorf("sing-box 进程退出: %s", exit.Reason)
+		for _, line := range exit.Stderr {
+			m.logger.Errorf("  %s", line)
+		}
 	} else {
 		m.logger.Info("sing-box 进程正常退出")
 	}
+
+	events := []Event{{
+		Type:    EventExited,
+		Message: "sing-box 进程退出: " + exit.Reason,
+		Exit:    exit,
+	}}
+
+	restart, delay, crashLoop := m.supervisor.next(exit)
+	if crashLoop {
+		m.crashLoop = true
+		m.logger.Errorf("sing-box 连续 %d 次快速退出，停止自动重启", m.supervisor.threshold)
+		return append(events, Event{
+			Type:    EventCrashLoop,
+			Message: fmt.Sprintf("sing-box 连续 %d 次快速退出，已停止自动重启", m.supervisor.threshold),
+			Exit:    exit,
+		})
+	}
+	if !restart {
+		return events
+	}
+
+	attempt := m.supervisor.attempt
+	m.logger.Warnf("%v 后第 %d 次重启 sing-box", delay, attempt)
+	m.restartTimer = time.AfterFunc(delay, m.restartAfterExit)
+
+	return append(events, Event{
+		Type:    EventRestarting,
+		Message: fmt.Sprintf("%v 后自动重启", delay),
+		Attempt: attempt,
+		Delay:   delay.Seconds(),
+	})
+}
+
+// restartAfterExit 退避结束后重新拉起进程
+func (m *Manager) restartAfterExit() {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+
+	m.mu.Lock()
+	timer := m.restartTimer
+	if timer == nil || m.isRunning {
+		// 已被取消或已手动启动
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	// 准备期间不持有 m.mu，状态仍为 restarting
+	plan, err := m.prepareStart()
+
+	m.mu.Lock()
+	if m.restartTimer != timer {
+		// 准备期间被 Stop 取消
+		m.mu.Unlock()
+		return
+	}
+	m.restartTimer = nil
+
+	if err == nil {
+		err = m.launch(plan)
+	}
+	var events []Event
+	if err != nil {
+		m.logger.Errorf("自动重启失败: %v", err)
+		events = m.handleExit(&ExitInfo{Code: -1, Reason: err.Error()})
+	} else {
+		events = []Event{{
+			Type:    EventStarted,
+			Message: "sing-box 已自动重启",
+			Attempt: m.supervisor.attempt,
+		}}
+	}
+	m.mu.Unlock()
+
+	for _, event := range events {
+		m.emit(event)
+	}
+}
+
+// detachTracker 取出当前的连接跟踪器，调用方需持有 m.mu，并在释放锁后停止它
+func (m *Manager) detachTracker() *connectionTracker {
+	tracker := m.tracker
+	m.tracker = nil
+	m.clash = nil
+	return tracker
+}
+
+// cancelRestart 取消待执行的自动重启，调用方需持有 m.mu
+func (m *Manager) cancelRestart() bool {
+	if m.restartTimer == nil {
+		return false
+	}
+	m.restartTimer.Stop()
+	m.restartTimer = nil
+	return true
+}
+
+// State 返回进程状态：running, restarting, crash_loop, stopped
+func (m *Manager) State() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.isRunning:
+		return "running"
+	case m.restartTimer != nil:
+		return "restarting"
+	case m.crashLoop:
+		return "crash_loop"
+	default:
+		return "stopped"
+	}
+}
+
+// LastExit 返回最近一次意外退出的信息
+func (m *Manager) LastExit() *ExitInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastExit
+}
+
+// createDefaultConfig 尚未拉取订阅时按应用配置生成不含节点的配置
+func createDefaultConfig(cfg *config.Config, version string) (map[string]interface{}, error) {
+	renderer := render.New(cfg)
+	renderer.SetVersion(version)
+	singboxConfig, err := renderer.Render(nil)
+	if err != nil {
+		return nil, fmt.Errorf("生成默认配置失败: %w", err)
+	}
+	return singboxConfig, nil
+}
+
+// CoreVersion 返回最近一次启动使用的内核版本
+func (m *Manager) CoreVersion() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.coreVersion
 }
 
-// createDefaultConfig 创建默认配置
-func (m *Manager) createDefaultConfig() map[string]interface{} {
-	return map[string]interface{}{
-		"log": map[string]interface{}{
-			"level":     "info",
-			"timestamp": true,
-		},
-		"dns": map[string]interface{}{
-			"servers": []map[string]interface{}{
-				{
-					"tag":     "remote",
-					"address": "https://1.1.1.1/dns-query",
-					"detour":  "direct",
-				},
-				{
-					"tag":     "local",
-					"address": "https://223.5.5.5/dns-query",
-					"detour":  "direct",
-				},
-			},
-			"rules": []map[string]interface{}{
-				{
-					"geosite": []string{"cn"},
-					"server":  "local",
-				},
-			},
-			"final": "remote",
-		},
-		"inbounds": []map[string]interface{}{
-			{
-				"type":        "mixed",
-				"tag":         "mixed-in",
-				"listen":      "127.0.0.1",
-				"listen_port": 7890,
-				"sniff":       true,
-			},
-		},
-		"outbounds": []map[string]interface{}{
-			{
-				"type": "direct",
-				"tag":  "direct",
-			},
-			{
-				"type": "block",
-				"tag":  "block",
-			},
-			{
-				"type": "dns",
-				"tag":  "dns-out",
-			},
-		},
-		"route": map[string]interface{}{
-			"rules": []map[string]interface{}{
-				{
-					"protocol": "dns",
-					"outbound": "dns-out",
-				},
-			},
-			"final": "direct",
-		},
+// Logs 返回 sing-box 日志缓冲
+func (m *Manager) Logs() *LogBuffer {
+	return m.logs
+}
+
+// SetConfig 替换应用配置，内核选择、守护策略和 Clash API 设置在下次启动或更新配置时生效。
+// 传入的配置此后不能再修改，需要修改时复制一份再调用 SetConfig
+func (m *Manager) SetConfig(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 守护策略不变时保留退避和快速失败计数
+	if m.config == nil || m.config.Singbox.Supervisor != cfg.Singbox.Supervisor {
+		m.supervisor = newSupervisor(cfg.Singbox.Supervisor)
 	}
+	m.config = cfg
+}
+
+// appConfig 返回当前应用配置，配置替换后不再修改，读取字段无需持有 m.mu
+func (m *Manager) appConfig() *config.Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.config
+}
+
+// SetRenderer 设置按内核版本生成 sing-box 配置的函数，每次启动前按探测到的版本调用，
+// 避免使用按其他版本生成的缓存配置
+func (m *Manager) SetRenderer(renderer func(version string) (map[string]interface{}, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.renderer = renderer
 }
 
 // SetLogger 设置日志记录器
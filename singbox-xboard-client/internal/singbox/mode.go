@@ -0,0 +1,88 @@
+package singbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// Mode 返回当前规则模式
+func (m *Manager) Mode() string {
+	return configMode(m.appConfig())
+}
+
+// configMode 返回配置中的规则模式，未设置时为 rule
+func configMode(cfg *config.Config) string {
+	if cfg.Rules.Mode == "" {
+		return config.ModeRule
+	}
+	return cfg.Rules.Mode
+}
+
+// SetMode 按新配置中的规则模式切换。运行中通过 Clash API 即时生效，
+// 成功后替换应用配置并写入 sing-box 配置的 default_mode，重载或重启后保持不变。
+// cfg 是调用方修改后的配置副本，保存应用配置由调用方负责
+func (m *Manager) SetMode(cfg *config.Config) error {
+	mode := configMode(cfg)
+	if err := config.ValidateMode(mode); err != nil {
+		return err
+	}
+
+	if m.IsRunning() {
+		client, err := m.ClashAPI()
+		if err != nil {
+			return err
+		}
+		if err := client.SetMode(mode); err != nil {
+			return fmt.Errorf("切换规则模式失败: %w", err)
+		}
+
+		// sing-box 对不认识的模式同样返回成功，需要读回确认
+		current, err := client.GetMode()
+		if err != nil {
+			return fmt.Errorf("确认规则模式失败: %w", err)
+		}
+		if current != mode {
+			return fmt.Errorf("sing-box 未接受规则模式 %s，当前为 %s", mode, current)
+		}
+	}
+
+	m.SetConfig(cfg)
+	if err := m.saveDefaultMode(cfg); err != nil {
+		m.logger.Warnf("保存默认规则模式失败: %v", err)
+	}
+
+	m.logger.Infof("规则模式已切换为 %s", mode)
+	return nil
+}
+
+// saveDefaultMode 更新已生成配置中的 Clash API 默认模式
+func (m *Manager) saveDefaultMode(cfg *config.Config) error {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	configPath := filepath.Join(config.GetConfigDir(), "singbox.json")
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	var singboxConfig map[string]interface{}
+	if err := json.Unmarshal(data, &singboxConfig); err != nil {
+		return fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	applyClashAPI(singboxConfig, cfg)
+	data, err = json.MarshalIndent(singboxConfig, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	return m.writeConfig(configPath, data, cfg)
+}
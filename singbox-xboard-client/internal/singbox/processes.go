@@ -0,0 +1,99 @@
+package singbox
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecentProcesses 最多记录的进程数
+const maxRecentProcesses = 200
+
+// ProcessInfo 最近发起过连接的进程
+type ProcessInfo struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	Connections  int       `json:"connections"` // 记录以来的连接数
+	LastOutbound string    `json:"last_outbound,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// processRecorder 从连接元数据中收集进程，超出上限时淘汰最久未见的
+type processRecorder struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[string]*ProcessInfo
+}
+
+func newProcessRecorder(limit int) *processRecorder {
+	return &processRecorder{
+		limit:   limit,
+		entries: make(map[string]*ProcessInfo),
+	}
+}
+
+// record 记录新连接的进程，需要 sing-box 开启 find_process 才有进程信息
+func (r *processRecorder) record(conns []Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, conn := range conns {
+		if conn.ProcessPath == "" {
+			continue
+		}
+
+		entry, ok := r.entries[conn.ProcessPath]
+		if !ok {
+			entry = &ProcessInfo{
+				Name:      processName(conn.ProcessPath),
+				Path:      conn.ProcessPath,
+				FirstSeen: conn.Start,
+			}
+			r.entries[conn.ProcessPath] = entry
+		}
+		entry.Connections++
+		entry.LastOutbound = conn.Outbound
+		if conn.Start.After(entry.LastSeen) {
+			entry.LastSeen = conn.Start
+		}
+	}
+
+	for len(r.entries) > r.limit {
+		var oldest *ProcessInfo
+		for _, entry := range r.entries {
+			if oldest == nil || entry.LastSeen.Before(oldest.LastSeen) {
+				oldest = entry
+			}
+		}
+		delete(r.entries, oldest.Path)
+	}
+}
+
+// list 按最近出现时间倒序返回
+func (r *processRecorder) list() []ProcessInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]ProcessInfo, 0, len(r.entries))
+	for _, entry := range r.entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+	return result
+}
+
+// processName 从路径中取出进程名，兼容 Windows 路径
+func processName(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	return filepath.Base(path)
+}
+
+// RecentProcesses 返回最近发起过连接的进程，可用于编写按进程分流的规则
+func (m *Manager) RecentProcesses() []ProcessInfo {
+	return m.processes.list()
+}
@@ -0,0 +1,92 @@
+package singbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// restartKeys 变更后无法热重载、必须完整重启的配置项
+var restartKeys = map[string]string{
+	"inbounds": "入站配置变更",
+}
+
+// Reload 通知运行中的 sing-box 重新加载配置文件，进程不退出
+func (m *Manager) Reload() error {
+	m.mu.Lock()
+	if !m.isRunning {
+		m.mu.Unlock()
+		return fmt.Errorf("sing-box 未运行")
+	}
+	process := m.process
+	m.mu.Unlock()
+
+	if err := reloadProcess(process.Process); err != nil {
+		return err
+	}
+
+	m.logger.Info("已通知 sing-box 热重载配置")
+	m.emit(Event{Type: EventReloaded, Message: "配置已热重载"})
+	return nil
+}
+
+// restartReason 比较两份配置，返回需要完整重启的原因，可以热重载时返回空
+func restartReason(previous, next []byte) string {
+	if len(previous) == 0 {
+		return ""
+	}
+
+	var oldConfig, newConfig map[string]interface{}
+	if err := json.Unmarshal(previous, &oldConfig); err != nil {
+		return "无法解析当前配置"
+	}
+	if err := json.Unmarshal(next, &newConfig); err != nil {
+		return "无法解析新配置"
+	}
+
+	for key, reason := range restartKeys {
+		if !reflect.DeepEqual(oldConfig[key], newConfig[key]) {
+			return reason
+		}
+	}
+
+	// 连接跟踪等功能使用启动时创建的 Clash API 客户端，地址或密钥变化后需要重新创建
+	if clashEndpoint(oldConfig) != clashEndpoint(newConfig) {
+		return "Clash API 地址或密钥变更"
+	}
+	return ""
+}
+
+// clashEndpoint 取出配置中 Clash API 的监听地址和密钥
+func clashEndpoint(singboxConfig map[string]interface{}) [2]interface{} {
+	experimental, _ := singboxConfig["experimental"].(map[string]interface{})
+	clashAPI, _ := experimental["clash_api"].(map[string]interface{})
+	return [2]interface{}{clashAPI["external_controller"], clashAPI["secret"]}
+}
+
+// writeConfig 先写入临时文件并用 cfg 选择的 sing-box check 校验，通过后再替换正式配置
+func (m *Manager) writeConfig(path string, data []byte, cfg *config.Config) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+
+	// 找不到内核时跳过校验，启动时会再次报错
+	if binary, err := findSingboxBinary(cfg); err == nil {
+		if output, err := exec.Command(binary, "check", "-c", tmp).CombinedOutput(); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("配置校验失败: %s", strings.TrimSpace(string(output)))
+		}
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	return nil
+}
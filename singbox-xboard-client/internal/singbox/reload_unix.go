@@ -0,0 +1,17 @@
+//go:build !windows
+
+package singbox
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// reloadProcess 发送 SIGHUP，sing-box 收到后会重新读取配置
+func reloadProcess(process *os.Process) error {
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("发送 SIGHUP 失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package singbox
+
+import (
+	"fmt"
+	"os"
+)
+
+// reloadProcess Windows 不支持 SIGHUP，只能完整重启
+func reloadProcess(process *os.Process) error {
+	return fmt.Errorf("Windows 不支持热重载")
+}
@@ -0,0 +1,189 @@
+package singbox
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// RestartPolicy 进程退出后的重启策略
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"      // 从不重启
+	RestartOnFailure RestartPolicy = "on-failure" // 异常退出时重启
+	RestartAlways    RestartPolicy = "always"     // 任何退出都重启
+)
+
+const (
+	defaultInitialBackoff     = time.Second
+	defaultMaxBackoff         = time.Minute
+	defaultCrashLoopThreshold = 5
+	defaultCrashLoopWindow    = 30 * time.Second
+
+	// stderrTailLines 退出时保留的 stderr 行数
+	stderrTailLines = 20
+)
+
+// ExitInfo 进程退出信息
+type ExitInfo struct {
+	Code   int      `json:"code"`             // 退出码，被信号终止时为 -1
+	Reason string   `json:"reason"`           // 退出原因描述
+	Uptime float64  `json:"uptime"`           // 本次运行时长（秒）
+	Stderr []string `json:"stderr,omitempty"` // 最后若干行 stderr 输出
+}
+
+// Failed 是否为异常退出
+func (e *ExitInfo) Failed() bool {
+	return e.Code != 0
+}
+
+// newExitInfo 根据 Wait 的返回值生成退出信息
+func newExitInfo(err error, uptime time.Duration, stderr []string) *ExitInfo {
+	info := &ExitInfo{
+		Uptime: uptime.Seconds(),
+		Stderr: stderr,
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		info.Reason = "exit status 0"
+	case errors.As(err, &exitErr):
+		info.Code = exitErr.ExitCode()
+		info.Reason = exitErr.ProcessState.String()
+	default:
+		info.Code = -1
+		info.Reason = err.Error()
+	}
+
+	return info
+}
+
+// supervisor 根据重启策略决定进程退出后的处理方式
+type supervisor struct {
+	policy         RestartPolicy
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	threshold      int
+	window         time.Duration
+
+	backoff       time.Duration
+	quickFailures int
+	attempt       int
+}
+
+// newSupervisor 创建进程守护，未配置的项使用默认值
+func newSupervisor(cfg config.SupervisorConfig) *supervisor {
+	s := &supervisor{
+		policy:         RestartPolicy(cfg.RestartPolicy),
+		initialBackoff: time.Duration(cfg.InitialBackoff) * time.Second,
+		maxBackoff:     time.Duration(cfg.MaxBackoff) * time.Second,
+		threshold:      cfg.CrashLoopThreshold,
+		window:         time.Duration(cfg.CrashLoopWindow) * time.Second,
+	}
+
+	switch s.policy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		s.policy = RestartOnFailure
+	}
+	if s.initialBackoff <= 0 {
+		s.initialBackoff = defaultInitialBackoff
+	}
+	if s.maxBackoff < s.initialBackoff {
+		s.maxBackoff = defaultMaxBackoff
+	}
+	if s.threshold <= 0 {
+		s.threshold = defaultCrashLoopThreshold
+	}
+	if s.window <= 0 {
+		s.window = defaultCrashLoopWindow
+	}
+
+	s.reset()
+	return s
+}
+
+// reset 手动启动时清空失败计数
+func (s *supervisor) reset() {
+	s.backoff = s.initialBackoff
+	s.quickFailures = 0
+	s.attempt = 0
+}
+
+// next 处理一次退出，返回是否重启、等待时长以及是否判定为崩溃循环
+func (s *supervisor) next(exit *ExitInfo) (restart bool, delay time.Duration, crashLoop bool) {
+	switch s.policy {
+	case RestartNever:
+		return false, 0, false
+	case RestartOnFailure:
+		if !exit.Failed() {
+			return false, 0, false
+		}
+	}
+
+	// 运行足够久说明之前的问题已恢复，重新计算退避
+	if time.Duration(exit.Uptime*float64(time.Second)) >= s.window {
+		s.backoff = s.initialBackoff
+		s.quickFailures = 0
+	} else {
+		s.quickFailures++
+		if s.quickFailures >= s.threshold {
+			return false, 0, true
+		}
+	}
+
+	delay = s.backoff
+	s.backoff *= 2
+	if s.backoff > s.maxBackoff {
+		s.backoff = s.maxBackoff
+	}
+	s.attempt++
+
+	return true, delay, false
+}
+
+// tailBuffer 按行保留最近的输出
+type tailBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	max     int
+	partial string
+}
+
+// newTailBuffer 创建输出缓冲
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write 实现 io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = splitLines(t.partial, p, func(line string) {
+		t.lines = append(t.lines, line)
+		if len(t.lines) > t.max {
+			t.lines = t.lines[len(t.lines)-t.max:]
+		}
+	})
+
+	return len(p), nil
+}
+
+// Lines 返回缓冲中的行
+func (t *tailBuffer) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]string, 0, len(t.lines)+1)
+	lines = append(lines, t.lines...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	return lines
+}
@@ -0,0 +1,107 @@
+package singbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+func TestSupervisorPolicy(t *testing.T) {
+	failed := &ExitInfo{Code: 1, Uptime: 1}
+	clean := &ExitInfo{Code: 0, Uptime: 1}
+
+	tests := []struct {
+		policy string
+		exit   *ExitInfo
+		want   bool
+	}{
+		{"never", failed, false},
+		{"on-failure", failed, true},
+		{"on-failure", clean, false},
+		{"always", clean, true},
+		{"", failed, true}, // 未配置时按 on-failure 处理
+		{"", clean, false},
+	}
+	for _, tt := range tests {
+		s := newSupervisor(config.SupervisorConfig{RestartPolicy: tt.policy})
+		if restart, _, _ := s.next(tt.exit); restart != tt.want {
+			t.Errorf("策略 %q 退出码 %d 时重启为 %v，期望 %v", tt.policy, tt.exit.Code, restart, tt.want)
+		}
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	s := newSupervisor(config.SupervisorConfig{
+		InitialBackoff:     1,
+		MaxBackoff:         5,
+		CrashLoopThreshold: 100,
+		CrashLoopWindow:    30,
+	})
+	quick := &ExitInfo{Code: 1, Uptime: 1}
+
+	want := []time.Duration{1, 2, 4, 5, 5}
+	for i, w := range want {
+		restart, delay, crashLoop := s.next(quick)
+		if !restart || crashLoop {
+			t.Fatalf("第 %d 次退出: restart=%v crashLoop=%v", i+1, restart, crashLoop)
+		}
+		if delay != w*time.Second {
+			t.Errorf("第 %d 次退出等待 %v，期望 %v", i+1, delay, w*time.Second)
+		}
+	}
+
+	// 运行超过窗口后退避重新计算
+	if _, delay, _ := s.next(&ExitInfo{Code: 1, Uptime: 60}); delay != time.Second {
+		t.Errorf("长时间运行后退出等待 %v，期望 1s", delay)
+	}
+
+	// 手动启动后同样重新计算
+	s.next(quick)
+	s.reset()
+	if _, delay, _ := s.next(quick); delay != time.Second {
+		t.Errorf("重置后等待 %v，期望 1s", delay)
+	}
+}
+
+func TestSupervisorCrashLoop(t *testing.T) {
+	s := newSupervisor(config.SupervisorConfig{CrashLoopThreshold: 3, CrashLoopWindow: 30})
+	quick := &ExitInfo{Code: 1, Uptime: 1}
+
+	for i := 0; i < 2; i++ {
+		if restart, _, crashLoop := s.next(quick); !restart || crashLoop {
+			t.Fatalf("第 %d 次快速失败: restart=%v crashLoop=%v", i+1, restart, crashLoop)
+		}
+	}
+
+	// 中间有一次长时间运行，快速失败重新计数
+	if restart, _, crashLoop := s.next(&ExitInfo{Code: 1, Uptime: 45}); !restart || crashLoop {
+		t.Fatalf("长时间运行后退出: restart=%v crashLoop=%v", restart, crashLoop)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, crashLoop := s.next(quick); crashLoop {
+			t.Fatalf("重新计数后第 %d 次快速失败即判定为崩溃循环", i+1)
+		}
+	}
+	if restart, _, crashLoop := s.next(quick); restart || !crashLoop {
+		t.Errorf("连续 3 次快速失败: restart=%v crashLoop=%v，期望判定为崩溃循环", restart, crashLoop)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	buf := newTailBuffer(2)
+	buf.Write([]byte("one\ntwo\nthr"))
+	buf.Write([]byte("ee\nfour"))
+
+	got := buf.Lines()
+	want := []string{"two", "three", "four"}
+	if len(got) != len(want) {
+		t.Fatalf("Lines() = %v，期望 %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Lines() = %v，期望 %v", got, want)
+			break
+		}
+	}
+}
@@ -2,6 +2,7 @@ package subscription
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,9 +12,14 @@ import (
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/render"
+	"github.com/your-username/singbox-xboard-client/internal/ruleset"
 	"github.com/your-username/singbox-xboard-client/pkg/xboard"
 )
 
+// ruleSetCheckInterval 检查规则集是否需要更新的间隔
+const ruleSetCheckInterval = time.Hour
+
 // Manager 订阅管理器
 type Manager struct {
 	config      *config.Config
@@ -23,15 +29,40 @@ type Manager struct {
 	mu          sync.RWMutex
 	lastUpdate  time.Time
 	lastConfig  map[string]interface{}
+	servers     []xboard.Server
+	coreVersion string
 	updateHooks []func(map[string]interface{})
+	userHooks   []func(*xboard.UserInfo)
+	fetchStats  FetchStats
+	excluded    map[string]bool // 不参与自动测速选择的节点
+	ruleSetOnce sync.Once       // 规则集后台更新只启动一次
+	stopOnce    sync.Once
+	stopCh      chan struct{}
+
+	// 生成和通知串行执行，更新钩子由单个协程按生成顺序调用，
+	// 未处理的旧配置被新配置替换，避免旧配置覆盖新配置
+	applyMu     sync.Mutex
+	updates     chan map[string]interface{}
+}
+
+// FetchStats 订阅拉取统计
+type FetchStats struct {
+	Success     int64     `json:"success"`
+	Failure     int64     `json:"failure"`
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
 }
 
 // NewManager 创建订阅管理器
 func NewManager() *Manager {
-	return &Manager{
+	m := &Manager{
 		logger:      logrus.New(),
 		updateHooks: make([]func(map[string]interface{}), 0),
+		stopCh:      make(chan struct{}),
+		updates:     make(chan map[string]interface{}, 1),
 	}
+	go m.deliverUpdates()
+	return m
 }
 
 // Initialize 初始化订阅管理器
@@ -62,9 +93,21 @@ func (m *Manager) Initialize(cfg *config.Config) error {
 		m.setupAutoUpdate()
 	}
 
+	m.ruleSetOnce.Do(func() {
+		go m.refreshRuleSets()
+	})
 	return nil
 }
 
+// SetConfig 替换应用配置，不重建订阅客户端和自动更新任务，
+// 用于修改规则、入站等只影响生成结果的设置，下次生成 sing-box 配置时生效。
+// 传入的配置此后不能再修改
+func (m *Manager) SetConfig(cfg *config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = cfg
+}
+
 // UpdateSubscription 更新订阅
 func (m *Manager) UpdateSubscription(url string) error {
 	m.logger.Info("开始更新订阅")
@@ -79,32 +122,26 @@ func (m *Manager) UpdateSubscription(url string) error {
 	client := xboard.NewClient(baseURL, token)
 	client.SetLogger(m.logger)
 
-	// 获取配置
-	singboxConfig, err := client.GetSingboxConfig()
+	// 获取节点
+	servers, err := m.fetchServers(client)
 	if err != nil {
-		return fmt.Errorf("获取配置失败: %w", err)
-	}
-
-	// 保存配置
-	if err := m.saveConfig(singboxConfig); err != nil {
-		return fmt.Errorf("保存配置失败: %w", err)
+		return err
 	}
 
-	// 更新状态
+	// 更新应用配置，修改副本以免影响持有原配置的调用方
 	m.mu.Lock()
-	m.lastUpdate = time.Now()
-	m.lastConfig = singboxConfig
 	m.client = client
-	
-	// 更新应用配置
 	if m.config != nil {
-		m.config.Subscription.URL = url
-		m.config.Subscription.Token = token
+		next := *m.config
+		next.Subscription.URL = url
+		next.Subscription.Token = token
+		m.config = &next
 	}
 	m.mu.Unlock()
 
-	// 触发更新钩子
-	m.notifyUpdate(singboxConfig)
+	if err := m.apply(servers); err != nil {
+		return err
+	}
 
 	m.logger.Info("订阅更新成功")
 	return nil
@@ -136,31 +173,176 @@ func (m *Manager) RefreshSubscription() error {
 
 	m.logger.Info("刷新订阅")
 
-	// 获取配置
-	singboxConfig, err := client.GetSingboxConfig()
+	servers, err := m.fetchServers(client)
+	if err != nil {
+		return err
+	}
+
+	if err := m.apply(servers); err != nil {
+		return err
+	}
+
+	m.logger.Info("订阅刷新成功")
+	return nil
+}
+
+// Rerender 使用缓存的节点和当前应用配置重新生成 sing-box 配置，
+// 应用配置变更后调用
+func (m *Manager) Rerender() error {
+	servers, err := m.cachedServers()
+	if err != nil {
+		return err
+	}
+
+	return m.apply(servers)
+}
+
+// cachedServers 返回最近一次获取的节点，尚未拉取过订阅时为空
+func (m *Manager) cachedServers() ([]xboard.Server, error) {
+	m.mu.RLock()
+	servers := m.servers
+	m.mu.RUnlock()
+
+	if servers != nil {
+		return servers, nil
+	}
+
+	cached, err := loadServers()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// fetchServers 从面板获取节点列表
+func (m *Manager) fetchServers(client *xboard.Client) ([]xboard.Server, error) {
+	sub, err := client.GetSubscription()
+
+	m.mu.Lock()
+	if err != nil {
+		m.fetchStats.Failure++
+		m.fetchStats.LastFailure = time.Now()
+	} else {
+		m.fetchStats.Success++
+		m.fetchStats.LastSuccess = time.Now()
+	}
+	m.mu.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("获取订阅失败: %w", err)
+	}
+	return sub.Servers, nil
+}
+
+// FetchStats 返回订阅拉取统计
+func (m *Manager) FetchStats() FetchStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fetchStats
+}
+
+// apply 生成 sing-box 配置并缓存节点，随后通知更新钩子
+func (m *Manager) apply(servers []xboard.Server) error {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	singboxConfig, err := m.render(servers)
 	if err != nil {
-		return fmt.Errorf("获取配置失败: %w", err)
+		return err
 	}
 
-	// 保存配置
-	if err := m.saveConfig(singboxConfig); err != nil {
-		return fmt.Errorf("保存配置失败: %w", err)
+	// singbox.json 由更新钩子校验后写入，这里只缓存节点
+	if err := saveServers(servers); err != nil {
+		m.logger.Warnf("缓存节点失败: %v", err)
 	}
 
 	// 更新状态
 	m.mu.Lock()
 	m.lastUpdate = time.Now()
 	m.lastConfig = singboxConfig
+	m.servers = servers
 	m.mu.Unlock()
 
 	// 触发更新钩子
 	m.notifyUpdate(singboxConfig)
+	return nil
+}
 
-	m.logger.Info("订阅刷新成功")
+// RuleSets 返回按当前应用配置创建的规则集管理器
+func (m *Manager) RuleSets() *ruleset.Manager {
+	m.mu.RLock()
+	cfg := m.config
+	m.mu.RUnlock()
+
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	manager := ruleset.NewManager(cfg.Singbox.Route)
+	manager.SetLogger(m.logger)
+	return manager
+}
+
+// Check 用缓存的节点按给定配置试生成 sing-box 配置，用于保存配置前校验
+func (m *Manager) Check(cfg *config.Config) error {
+	servers, err := m.cachedServers()
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	version := m.coreVersion
+	m.mu.RUnlock()
+
+	renderer := render.New(cfg)
+	renderer.SetVersion(version)
+	if _, err := renderer.Render(servers); err != nil {
+		return err
+	}
 	return nil
 }
 
-// GetUserInfo 获取用户信息
+// SetCoreVersion 设置目标 sing-box 版本，返回版本是否发生变化
+func (m *Manager) SetCoreVersion(version string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.coreVersion == version {
+		return false
+	}
+	m.coreVersion = version
+	return true
+}
+
+// RenderFor 将目标版本设为 version，并用缓存的节点生成 sing-box 配置，
+// 供启动 sing-box 前按实际内核版本重新生成
+func (m *Manager) RenderFor(version string) (map[string]interface{}, error) {
+	m.SetCoreVersion(version)
+	servers, err := m.cachedServers()
+	if err != nil {
+		return nil, err
+	}
+	return m.render(servers)
+}
+
+// render 按当前应用配置生成 sing-box 配置
+func (m *Manager) render(servers []xboard.Server) (map[string]interface{}, error) {
+	m.mu.RLock()
+	cfg := m.config
+	version := m.coreVersion
+	excluded := m.excluded
+	m.mu.RUnlock()
+
+	renderer := render.New(cfg)
+	renderer.SetVersion(version)
+	renderer.SetExcluded(excluded)
+	singboxConfig, err := renderer.Render(servers)
+	if err != nil {
+		return nil, fmt.Errorf("生成配置失败: %w", err)
+	}
+	return singboxConfig, nil
+}
+
+// GetUserInfo 获取用户信息，成功后通知用户信息钩子
 func (m *Manager) GetUserInfo() (*xboard.UserInfo, error) {
 	m.mu.RLock()
 	client := m.client
@@ -170,7 +352,12 @@ func (m *Manager) GetUserInfo() (*xboard.UserInfo, error) {
 		return nil, fmt.Errorf("未配置订阅")
 	}
 
-	return client.GetUserInfo()
+	info, err := client.GetUserInfo()
+	if err != nil {
+		return nil, err
+	}
+	m.notifyUserInfo(info)
+	return info, nil
 }
 
 // GetNodeList 获取节点列表
@@ -187,7 +374,7 @@ func (m *Manager) GetNodeList() ([]xboard.NodeInfo, error) {
 }
 
 // ReportTraffic 上报流量
-func (m *Manager) ReportTraffic(upload, download int64, nodeID int) error {
+func (m *Manager) ReportTraffic(report xboard.TrafficReport) error {
 	m.mu.RLock()
 	client := m.client
 	m.mu.RUnlock()
@@ -196,16 +383,76 @@ func (m *Manager) ReportTraffic(upload, download int64, nodeID int) error {
 		return fmt.Errorf("未配置订阅")
 	}
 
-	return client.ReportTraffic(upload, download, nodeID)
+	return client.ReportTraffic(report)
+}
+
+// NodeIDs 返回生成配置中订阅节点的出站标签到节点 ID 的映射
+func (m *Manager) NodeIDs() (map[string]int, error) {
+	servers, err := m.cachedServers()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	cfg := m.config
+	m.mu.RUnlock()
+
+	ids := make(map[string]int, len(servers))
+	for i, tag := range render.New(cfg).NodeTags(servers) {
+		ids[tag] = servers[i].ID
+	}
+	return ids, nil
+}
+
+// NodeTags 返回缓存节点在生成配置中的出站标签
+func (m *Manager) NodeTags() ([]string, error) {
+	servers, err := m.cachedServers()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	cfg := m.config
+	m.mu.RUnlock()
+
+	return render.New(cfg).NodeTags(servers), nil
+}
+
+// SetExcludedNodes 设置不参与自动测速选择的节点，返回是否发生变化，变化后需调用 Rerender 生效
+func (m *Manager) SetExcludedNodes(tags map[string]bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(tags) == len(m.excluded) {
+		same := true
+		for tag := range tags {
+			if !m.excluded[tag] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	m.excluded = tags
+	return true
 }
 
-// OnUpdate 注册更新钩子
+// OnUpdate 注册更新钩子，钩子在同一个协程中按生成顺序依次调用
 func (m *Manager) OnUpdate(hook func(map[string]interface{})) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.updateHooks = append(m.updateHooks, hook)
 }
 
+// OnUserInfo 注册用户信息钩子，每次获取到用户信息后调用
+func (m *Manager) OnUserInfo(hook func(*xboard.UserInfo)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.userHooks = append(m.userHooks, hook)
+}
+
 // setupAutoUpdate 设置自动更新
 func (m *Manager) setupAutoUpdate() {
 	if m.cron != nil {
@@ -221,56 +468,119 @@ func (m *Manager) setupAutoUpdate() {
 		if err := m.RefreshSubscription(); err != nil {
 			m.logger.Errorf("自动更新失败: %v", err)
 		}
+
+		// 顺便刷新用户信息，没有打开界面时也能检查流量和到期提醒
+		if _, err := m.GetUserInfo(); err != nil {
+			m.logger.Warnf("获取用户信息失败: %v", err)
+		}
 	})
 
 	m.cron.Start()
 	m.logger.Infof("已启用自动更新，间隔: %d 分钟", m.config.Subscription.UpdateInterval)
 }
 
-// saveConfig 保存配置到文件
-func (m *Manager) saveConfig(singboxConfig map[string]interface{}) error {
-	// 获取配置目录
-	configDir := config.GetConfigDir()
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("创建配置目录失败: %w", err)
+// refreshRuleSets 在后台定期下载未缓存或过期的规则集，有更新时重新生成配置以引用本地缓存。
+// 下载不经过代理，失败时生成的配置仍使用远程规则集，由 sing-box 通过 download_detour 下载
+func (m *Manager) refreshRuleSets() {
+	ticker := time.NewTicker(ruleSetCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		refreshed, err := m.RuleSets().RefreshStale()
+		if err != nil {
+			m.logger.Warnf("更新规则集失败: %v", err)
+		}
+		m.mu.RLock()
+		applied := m.lastConfig != nil
+		m.mu.RUnlock()
+		if refreshed > 0 && applied {
+			if err := m.Rerender(); err != nil {
+				m.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.stopCh:
+			return
+		}
 	}
+}
 
-	// 保存为 JSON 文件
-	configPath := filepath.Join(configDir, "singbox.json")
-	data, err := json.MarshalIndent(singboxConfig, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化配置失败: %w", err)
+// notifyUpdate 通知更新，调用方需持有 m.applyMu。
+// 上一个配置尚未交给钩子时直接替换，钩子只会收到最新的配置
+func (m *Manager) notifyUpdate(singboxConfig map[string]interface{}) {
+	select {
+	case <-m.updates:
+	default:
 	}
+	m.updates <- singboxConfig
+}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("写入配置文件失败: %w", err)
+// deliverUpdates 按生成顺序依次调用更新钩子，上一次调用返回后才处理下一个配置
+func (m *Manager) deliverUpdates() {
+	for {
+		select {
+		case singboxConfig := <-m.updates:
+			m.mu.RLock()
+			hooks := make([]func(map[string]interface{}), len(m.updateHooks))
+			copy(hooks, m.updateHooks)
+			m.mu.RUnlock()
+
+			for _, hook := range hooks {
+				m.callUpdateHook(hook, singboxConfig)
+			}
+		case <-m.stopCh:
+			return
+		}
 	}
+}
 
-	m.logger.Debugf("配置已保存到: %s", configPath)
-	return nil
+// callUpdateHook 调用单个更新钩子，钩子 panic 时记录日志并继续
+func (m *Manager) callUpdateHook(hook func(map[string]interface{}), singboxConfig map[string]interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Errorf("更新钩子执行失败: %v", r)
+		}
+	}()
+	hook(singboxConfig)
 }
 
-// notifyUpdate 通知更新
-func (m *Manager) notifyUpdate(singboxConfig map[string]interface{}) {
+// notifyUserInfo 通知用户信息钩子
+func (m *Manager) notifyUserInfo(info *xboard.UserInfo) {
 	m.mu.RLock()
-	hooks := make([]func(map[string]interface{}), len(m.updateHooks))
-	copy(hooks, m.updateHooks)
+	hooks := make([]func(*xboard.UserInfo), len(m.userHooks))
+	copy(hooks, m.userHooks)
 	m.mu.RUnlock()
 
 	for _, hook := range hooks {
-		go func(h func(map[string]interface{})) {
+		go func(h func(*xboard.UserInfo)) {
 			defer func() {
 				if r := recover(); r != nil {
-					m.logger.Errorf("更新钩子执行失败: %v", r)
+					m.logger.Errorf("用户信息钩子执行失败: %v", r)
 				}
 			}()
-			h(singboxConfig)
+			h(info)
 		}(hook)
 	}
 }
 
-// LoadCachedConfig 加载缓存的配置
+// LoadCachedConfig 加载缓存的配置，有缓存节点时按当前应用配置重新生成
 func (m *Manager) LoadCachedConfig() (map[string]interface{}, error) {
+	if servers, err := loadServers(); err == nil {
+		cfg, err := m.render(servers)
+		if err != nil {
+			return nil, err
+		}
+
+		m.mu.Lock()
+		m.lastConfig = cfg
+		m.servers = servers
+		m.mu.Unlock()
+
+		return cfg, nil
+	}
+
 	configPath := filepath.Join(config.GetConfigDir(), "singbox.json")
 	
 	data, err := os.ReadFile(configPath)
@@ -290,8 +600,40 @@ func (m *Manager) LoadCachedConfig() (map[string]interface{}, error) {
 	return cfg, nil
 }
 
+// saveServers 缓存订阅节点，配置变更后无需重新拉取即可重新生成
+func saveServers(servers []xboard.Server) error {
+	data, err := json.MarshalIndent(servers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化节点失败: %w", err)
+	}
+
+	path := filepath.Join(config.GetConfigDir(), "subscription.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入节点缓存失败: %w", err)
+	}
+	return nil
+}
+
+// loadServers 读取缓存的订阅节点
+func loadServers() ([]xboard.Server, error) {
+	path := filepath.Join(config.GetConfigDir(), "subscription.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取节点缓存失败: %w", err)
+	}
+
+	var servers []xboard.Server
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("解析节点缓存失败: %w", err)
+	}
+	return servers, nil
+}
+
 // Stop 停止订阅管理器
 func (m *Manager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
 	if m.cron != nil {
 		m.cron.Stop()
 		m.logger.Info("已停止自动更新")
@@ -0,0 +1,61 @@
+package subscription
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNotifyUpdateOrder(t *testing.T) {
+	m := NewManager()
+	defer m.Stop()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m.SetLogger(logger)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	received := make(chan int, 10)
+	m.OnUpdate(func(singboxConfig map[string]interface{}) {
+		version := singboxConfig["version"].(int)
+		if version == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		received <- version
+	})
+	// panic 的钩子不影响其他钩子和后续配置
+	m.OnUpdate(func(map[string]interface{}) { panic("钩子失败") })
+
+	notify := func(version int) {
+		m.applyMu.Lock()
+		defer m.applyMu.Unlock()
+		m.notifyUpdate(map[string]interface{}{"version": version})
+	}
+
+	notify(1)
+	<-started
+
+	// 第一个配置处理期间的配置只保留最新的一个
+	notify(2)
+	notify(3)
+	close(release)
+
+	for _, want := range []int{1, 3} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("钩子收到版本 %d，期望 %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待版本 %d 超时", want)
+		}
+	}
+	select {
+	case got := <-received:
+		t.Errorf("钩子多收到了版本 %d", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
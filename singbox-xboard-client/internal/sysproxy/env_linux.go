@@ -0,0 +1,83 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envFileName 默认写入 systemd environment.d 的文件名，新登录的会话生效
+const envFileName = "90-singbox-xboard-proxy.conf"
+
+// envBackend 将代理写入环境变量文件，供终端和不读取桌面设置的程序使用
+type envBackend struct {
+	path string
+}
+
+func (b *envBackend) name() string {
+	return BackendEnv
+}
+
+func (b *envBackend) available() bool {
+	return b.file() != ""
+}
+
+func (b *envBackend) file() string {
+	if b.path != "" {
+		return b.path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "environment.d", envFileName)
+}
+
+// snapshot 记录文件原内容，原来不存在时恢复为删除
+func (b *envBackend) snapshot() (map[string]string, error) {
+	data, err := os.ReadFile(b.file())
+	if os.IsNotExist(err) {
+		return map[string]string{"exists": "false"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"exists": "true", "content": string(data)}, nil
+}
+
+func (b *envBackend) apply(settings Settings) error {
+	var lines []string
+	add := func(key, value string) {
+		lines = append(lines, key+"="+value, strings.ToUpper(key)+"="+value)
+	}
+	add("http_proxy", settings.httpURL())
+	add("https_proxy", settings.httpURL())
+	if settings.SOCKS {
+		add("all_proxy", settings.socksURL())
+	}
+	add("no_proxy", strings.Join(settings.Bypass, ","))
+
+	content := "# 由 singbox-xboard 生成，停止代理后自动恢复\n" + strings.Join(lines, "\n") + "\n"
+	return b.write(content)
+}
+
+func (b *envBackend) restore(saved map[string]string) error {
+	if saved["exists"] == "true" {
+		return b.write(saved["content"])
+	}
+	if err := os.Remove(b.file()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *envBackend) write(content string) error {
+	path := b.file()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
@@ -0,0 +1,100 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gnomeKeys 需要备份的 gsettings 键，恢复时按顺序写回
+var gnomeKeys = [][2]string{
+	{"org.gnome.system.proxy", "mode"},
+	{"org.gnome.system.proxy", "ignore-hosts"},
+	{"org.gnome.system.proxy.http", "host"},
+	{"org.gnome.system.proxy.http", "port"},
+	{"org.gnome.system.proxy.https", "host"},
+	{"org.gnome.system.proxy.https", "port"},
+	{"org.gnome.system.proxy.socks", "host"},
+	{"org.gnome.system.proxy.socks", "port"},
+}
+
+// gnomeBackend 通过 gsettings 设置 GNOME 及其衍生桌面的代理
+type gnomeBackend struct {
+	runner Runner
+}
+
+func (b *gnomeBackend) name() string {
+	return BackendGNOME
+}
+
+func (b *gnomeBackend) available() bool {
+	_, err := b.runner.Run("gsettings", "get", "org.gnome.system.proxy", "mode")
+	return err == nil
+}
+
+// snapshot 保存 GVariant 文本形式的原值，可直接传给 gsettings set
+func (b *gnomeBackend) snapshot() (map[string]string, error) {
+	saved := make(map[string]string, len(gnomeKeys))
+	for _, key := range gnomeKeys {
+		value, err := b.runner.Run("gsettings", "get", key[0], key[1])
+		if err != nil {
+			return nil, err
+		}
+		saved[key[0]+" "+key[1]] = value
+	}
+	return saved, nil
+}
+
+func (b *gnomeBackend) apply(settings Settings) error {
+	socksHost, socksPort := "", 0
+	if settings.SOCKS {
+		socksHost, socksPort = settings.Host, settings.Port
+	}
+
+	values := [][3]string{
+		{"org.gnome.system.proxy.http", "host", gvariantString(settings.Host)},
+		{"org.gnome.system.proxy.http", "port", fmt.Sprint(settings.Port)},
+		{"org.gnome.system.proxy.https", "host", gvariantString(settings.Host)},
+		{"org.gnome.system.proxy.https", "port", fmt.Sprint(settings.Port)},
+		{"org.gnome.system.proxy.socks", "host", gvariantString(socksHost)},
+		{"org.gnome.system.proxy.socks", "port", fmt.Sprint(socksPort)},
+		{"org.gnome.system.proxy", "ignore-hosts", gvariantList(settings.Bypass)},
+		// 地址写好后再切换模式
+		{"org.gnome.system.proxy", "mode", gvariantString("manual")},
+	}
+	for _, v := range values {
+		if _, err := b.runner.Run("gsettings", "set", v[0], v[1], v[2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *gnomeBackend) restore(saved map[string]string) error {
+	for _, key := range gnomeKeys {
+		value, ok := saved[key[0]+" "+key[1]]
+		if !ok {
+			continue
+		}
+		if _, err := b.runner.Run("gsettings", "set", key[0], key[1], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gvariantString 返回 GVariant 字符串字面量
+func gvariantString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// gvariantList 返回 GVariant 字符串数组字面量
+func gvariantList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = gvariantString(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
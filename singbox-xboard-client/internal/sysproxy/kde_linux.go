@@ -0,0 +1,118 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KDE 代理设置所在的配置文件和分组
+const (
+	kdeFile  = "kioslaverc"
+	kdeGroup = "Proxy Settings"
+)
+
+// kdeKeys 需要备份的键，ProxyType 放在最前面，恢复时先关闭代理
+var kdeKeys = []string{"ProxyType", "httpProxy", "httpsProxy", "socksProxy", "NoProxyFor"}
+
+// kdeBackend 通过 kwriteconfig 设置 KDE Plasma 的代理
+type kdeBackend struct {
+	runner Runner
+	suffix string // 可用的 kreadconfig/kwriteconfig 版本号后缀
+}
+
+func (b *kdeBackend) name() string {
+	return BackendKDE
+}
+
+// available 优先使用 Plasma 6 的工具
+func (b *kdeBackend) available() bool {
+	for _, suffix := range []string{"6", "5"} {
+		if _, err := b.runner.Run("kreadconfig"+suffix, "--file", kdeFile, "--group", kdeGroup, "--key", "ProxyType"); err == nil {
+			b.suffix = suffix
+			return true
+		}
+	}
+	return false
+}
+
+func (b *kdeBackend) snapshot() (map[string]string, error) {
+	saved := make(map[string]string, len(kdeKeys))
+	for _, key := range kdeKeys {
+		value, err := b.read(key)
+		if err != nil {
+			return nil, err
+		}
+		saved[key] = value
+	}
+	return saved, nil
+}
+
+func (b *kdeBackend) apply(settings Settings) error {
+	// KDE 使用空格分隔主机和端口
+	host := settings.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	address := fmt.Sprintf("%s %d", host, settings.Port)
+
+	socks := ""
+	if settings.SOCKS {
+		socks = "socks://" + address
+	}
+
+	values := [][2]string{
+		{"httpProxy", "http://" + address},
+		{"httpsProxy", "http://" + address},
+		{"socksProxy", socks},
+		{"NoProxyFor", strings.Join(settings.Bypass, ",")},
+		{"ProxyType", "1"}, // 手动配置
+	}
+	for _, v := range values {
+		if err := b.write(v[0], v[1]); err != nil {
+			return err
+		}
+	}
+	b.notify()
+	return nil
+}
+
+func (b *kdeBackend) restore(saved map[string]string) error {
+	if b.suffix == "" && !b.available() {
+		return fmt.Errorf("找不到 kwriteconfig")
+	}
+	for _, key := range kdeKeys {
+		value, ok := saved[key]
+		if !ok {
+			continue
+		}
+		if err := b.write(key, value); err != nil {
+			return err
+		}
+	}
+	b.notify()
+	return nil
+}
+
+func (b *kdeBackend) read(key string) (string, error) {
+	return b.runner.Run("kreadconfig"+b.suffix, "--file", kdeFile, "--group", kdeGroup, "--key", key)
+}
+
+// write 写入配置，空值删除该键以还原为未设置状态
+func (b *kdeBackend) write(key, value string) error {
+	args := []string{"--file", kdeFile, "--group", kdeGroup, "--key", key}
+	if value == "" {
+		args = append(args, "--delete")
+	} else {
+		args = append(args, value)
+	}
+	_, err := b.runner.Run("kwriteconfig"+b.suffix, args...)
+	return err
+}
+
+// notify 通知已运行的程序重新读取代理设置，失败不影响设置本身
+func (b *kdeBackend) notify() {
+	b.runner.Run("dbus-send", "--type=signal", "/KIO/Scheduler",
+		"org.kde.KIO.Scheduler.reparseSlaveConfiguration", "string:")
+}
@@ -0,0 +1,362 @@
+// Package sysproxy 在 sing-box 运行期间把桌面系统代理指向本地入站，停止后恢复原有设置
+package sysproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// 系统代理的设置方式
+const (
+	BackendGNOME = "gnome" // gsettings
+	BackendKDE   = "kde"   // kwriteconfig
+	BackendEnv   = "env"   // 环境变量文件
+)
+
+const (
+	defaultHost = "127.0.0.1"
+	defaultPort = 7890
+)
+
+// DefaultBypass 默认不走代理的地址
+var DefaultBypass = []string{
+	"localhost",
+	"127.0.0.0/8",
+	"::1",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"*.local",
+}
+
+// Runner 执行外部命令并返回标准输出，替换后可在没有桌面环境时测试
+type Runner interface {
+	Run(name string, args ...string) (string, error)
+}
+
+// execRunner 直接执行系统命令
+type execRunner struct{}
+
+func (execRunner) Run(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("执行 %s 失败: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Settings 要设置的代理地址
+type Settings struct {
+	Host   string   `json:"host"`
+	Port   int      `json:"port"`
+	SOCKS  bool     `json:"socks"` // 入站是否同时支持 SOCKS
+	Bypass []string `json:"bypass"`
+}
+
+// httpURL 返回 http://host:port 形式的地址
+func (s Settings) httpURL() string {
+	return "http://" + net.JoinHostPort(s.Host, fmt.Sprint(s.Port))
+}
+
+// socksURL 返回 socks5://host:port 形式的地址
+func (s Settings) socksURL() string {
+	return "socks5://" + net.JoinHostPort(s.Host, fmt.Sprint(s.Port))
+}
+
+// FromConfig 根据入站配置确定代理地址，使用第一个启用的 mixed 或 http 入站
+func FromConfig(cfg *config.Config) (Settings, error) {
+	settings := Settings{
+		Host:   defaultHost,
+		Port:   defaultPort,
+		SOCKS:  true,
+		Bypass: cfg.SystemProxy.Bypass,
+	}
+	if len(settings.Bypass) == 0 {
+		settings.Bypass = DefaultBypass
+	}
+
+	// 未配置入站时渲染器只生成默认的 mixed 入站
+	if len(cfg.Singbox.Inbounds) == 0 {
+		return settings, nil
+	}
+
+	for _, in := range cfg.Singbox.Inbounds {
+		if in.Disabled || (in.Type != "mixed" && in.Type != "http") {
+			continue
+		}
+
+		// 监听全部地址时通过回环地址访问
+		switch in.Listen {
+		case "", "0.0.0.0", "::":
+		default:
+			settings.Host = in.Listen
+		}
+		if in.ListenPort != 0 {
+			settings.Port = in.ListenPort
+		} else if in.Type != "mixed" {
+			return Settings{}, fmt.Errorf("入站 %s 未设置端口", in.Tag)
+		}
+		settings.SOCKS = in.Type == "mixed"
+		return settings, nil
+	}
+	return Settings{}, fmt.Errorf("没有可用作系统代理的 mixed 或 http 入站")
+}
+
+// backend 一种系统代理设置方式
+type backend interface {
+	name() string
+	// available 当前环境能否使用该方式
+	available() bool
+	// snapshot 读取当前设置，用于之后恢复
+	snapshot() (map[string]string, error)
+	apply(settings Settings) error
+	restore(saved map[string]string) error
+}
+
+// backupState 修改前的系统代理设置，写入磁盘以便进程崩溃后恢复
+type backupState struct {
+	CreatedAt time.Time                    `json:"created_at"`
+	Backends  map[string]map[string]string `json:"backends"`
+}
+
+// Controller 系统代理控制器
+type Controller struct {
+	mu        sync.Mutex
+	cfg       config.SystemProxyConfig
+	runner    Runner
+	statePath string
+	active    bool
+	logger    *logrus.Logger
+}
+
+// NewController 创建系统代理控制器，原设置备份在配置目录下的 sysproxy.json
+func NewController(cfg config.SystemProxyConfig) *Controller {
+	return &Controller{
+		cfg:       cfg,
+		runner:    execRunner{},
+		statePath: filepath.Join(config.GetConfigDir(), "sysproxy.json"),
+		logger:    logrus.New(),
+	}
+}
+
+// SetRunner 替换执行外部命令的方式
+func (c *Controller) SetRunner(runner Runner) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runner = runner
+}
+
+// SetLogger 设置日志记录器
+func (c *Controller) SetLogger(logger *logrus.Logger) {
+	c.logger = logger
+}
+
+// SetConfig 更新系统代理配置，下次设置时生效
+func (c *Controller) SetConfig(cfg config.SystemProxyConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+}
+
+// Active 系统代理当前是否由本程序设置
+func (c *Controller) Active() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// Enable 备份当前设置后将系统代理指向 settings，已设置时只更新地址。
+// 任一设置方式失败时恢复全部备份，不保留部分生效的设置
+func (c *Controller) Enable(settings Settings) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	backends, err := c.backends()
+	if err != nil {
+		return err
+	}
+
+	// 已有备份说明上次未恢复，此时的系统设置是本程序写入的，不能覆盖原备份
+	state, err := c.loadState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &backupState{
+			CreatedAt: time.Now(),
+			Backends:  make(map[string]map[string]string),
+		}
+	}
+	for _, b := range backends {
+		if _, ok := state.Backends[b.name()]; ok {
+			continue
+		}
+		saved, err := b.snapshot()
+		if err != nil {
+			c.logger.Warnf("读取 %s 代理设置失败，跳过: %v", b.name(), err)
+			continue
+		}
+		state.Backends[b.name()] = saved
+	}
+
+	if len(state.Backends) == 0 {
+		return fmt.Errorf("无法读取任何系统代理设置")
+	}
+
+	// 先落盘再修改，修改过程中崩溃也能恢复
+	if err := c.saveState(state); err != nil {
+		return err
+	}
+
+	for _, b := range backends {
+		if _, ok := state.Backends[b.name()]; !ok {
+			continue
+		}
+		if err := b.apply(settings); err != nil {
+			err = fmt.Errorf("%s: %w", b.name(), err)
+			if rollbackErr := c.disable(); rollbackErr != nil {
+				return errors.Join(err, fmt.Errorf("恢复原有设置失败: %w", rollbackErr))
+			}
+			return err
+		}
+		c.logger.Infof("已通过 %s 设置系统代理: %s", b.name(), settings.httpURL())
+	}
+	c.active = true
+	return nil
+}
+
+// Disable 恢复备份的系统代理设置，没有备份时不做任何事。
+// 启动时调用可恢复上次异常退出遗留的设置
+func (c *Controller) Disable() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.disable()
+}
+
+// disable 恢复备份，调用方需持有 mu
+func (c *Controller) disable() error {
+	state, err := c.loadState()
+	if err != nil || state == nil {
+		c.active = false
+		return err
+	}
+
+	known := make(map[string]backend)
+	for _, b := range c.allBackends() {
+		known[b.name()] = b
+	}
+
+	var errs []error
+	for name, saved := range state.Backends {
+		b, ok := known[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("不支持的设置方式: %s", name))
+			continue
+		}
+		if err := b.restore(saved); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		delete(state.Backends, name)
+		c.logger.Infof("已恢复 %s 系统代理设置", name)
+	}
+	c.active = false
+
+	// 恢复失败的部分保留在备份中，下次再试
+	if len(state.Backends) > 0 {
+		if err := c.saveState(state); err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+	if err := os.Remove(c.statePath); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("删除备份失败: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// backends 返回配置允许且当前环境可用的设置方式
+func (c *Controller) backends() ([]backend, error) {
+	all := c.allBackends()
+	if len(all) == 0 {
+		return nil, fmt.Errorf("当前系统不支持设置系统代理")
+	}
+
+	wanted := make(map[string]bool, len(c.cfg.Backends))
+	for _, name := range c.cfg.Backends {
+		wanted[name] = true
+	}
+
+	known := make(map[string]bool, len(all))
+	var result []backend
+	for _, b := range all {
+		known[b.name()] = true
+		if len(wanted) > 0 && !wanted[b.name()] {
+			continue
+		}
+		if b.available() {
+			result = append(result, b)
+		}
+	}
+	for _, name := range c.cfg.Backends {
+		if !known[name] {
+			return nil, fmt.Errorf("不支持的设置方式: %s", name)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("没有可用的系统代理设置方式")
+	}
+	return result, nil
+}
+
+// loadState 读取备份，不存在时返回 nil
+func (c *Controller) loadState() (*backupState, error) {
+	data, err := os.ReadFile(c.statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取系统代理备份失败: %w", err)
+	}
+
+	var state backupState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析系统代理备份失败: %w", err)
+	}
+	if state.Backends == nil {
+		state.Backends = make(map[string]map[string]string)
+	}
+	return &state, nil
+}
+
+// saveState 原子写入备份
+func (c *Controller) saveState(state *backupState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化系统代理备份失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.statePath), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	tmp := c.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入系统代理备份失败: %w", err)
+	}
+	if err := os.Rename(tmp, c.statePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入系统代理备份失败: %w", err)
+	}
+	return nil
+}
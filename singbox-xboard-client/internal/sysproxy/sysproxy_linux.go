@@ -0,0 +1,12 @@
+//go:build linux
+
+package sysproxy
+
+// allBackends 返回 Linux 下所有设置方式，恢复时也按名称在其中查找
+func (c *Controller) allBackends() []backend {
+	return []backend{
+		&gnomeBackend{runner: c.runner},
+		&kdeBackend{runner: c.runner},
+		&envBackend{path: c.cfg.EnvFile},
+	}
+}
@@ -0,0 +1,250 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// fakeRunner 在内存中模拟 gsettings 和 kreadconfig/kwriteconfig
+type fakeRunner struct {
+	values map[string]string
+	fail   func(name string, args []string) bool // 返回 true 时该命令失败
+}
+
+func newFakeRunner() *fakeRunner {
+	values := map[string]string{
+		"kde ProxyType":  "0",
+		"kde NoProxyFor": "example.com",
+	}
+	for _, key := range gnomeKeys {
+		values[key[0]+" "+key[1]] = "''"
+	}
+	values["org.gnome.system.proxy mode"] = "'none'"
+	return &fakeRunner{values: values}
+}
+
+func (r *fakeRunner) Run(name string, args ...string) (string, error) {
+	if r.fail != nil && r.fail(name, args) {
+		return "", fmt.Errorf("%s 执行失败", name)
+	}
+	switch name {
+	case "gsettings":
+		key := args[1] + " " + args[2]
+		if args[0] == "set" {
+			r.values[key] = args[3]
+		}
+		return r.values[key], nil
+	case "kreadconfig6":
+		return r.values["kde "+args[5]], nil
+	case "kwriteconfig6":
+		key := "kde " + args[5]
+		if args[6] == "--delete" {
+			delete(r.values, key)
+		} else {
+			r.values[key] = args[6]
+		}
+		return "", nil
+	case "dbus-send":
+		return "", nil
+	}
+	return "", fmt.Errorf("找不到 %s", name)
+}
+
+func (r *fakeRunner) snapshot() map[string]string {
+	copied := make(map[string]string, len(r.values))
+	for k, v := range r.values {
+		copied[k] = v
+	}
+	return copied
+}
+
+func newTestController(t *testing.T, runner Runner, dir string) *Controller {
+	t.Helper()
+	c := NewController(config.SystemProxyConfig{EnvFile: filepath.Join(dir, "proxy.conf")})
+	c.statePath = filepath.Join(dir, "sysproxy.json")
+	c.SetRunner(runner)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	c.SetLogger(logger)
+	return c
+}
+
+var testSettings = Settings{Host: "127.0.0.1", Port: 7890, SOCKS: true, Bypass: DefaultBypass}
+
+func TestEnableDisable(t *testing.T) {
+	dir := t.TempDir()
+	runner := newFakeRunner()
+	original := runner.snapshot()
+	c := newTestController(t, runner, dir)
+
+	if err := c.Enable(testSettings); err != nil {
+		t.Fatalf("Enable 失败: %v", err)
+	}
+	if !c.Active() {
+		t.Error("Enable 后应当为已设置")
+	}
+	if got := runner.values["org.gnome.system.proxy mode"]; got != "'manual'" {
+		t.Errorf("GNOME 代理模式为 %s，期望 'manual'", got)
+	}
+	if got := runner.values["kde httpProxy"]; got != "http://127.0.0.1 7890" {
+		t.Errorf("KDE httpProxy 为 %q", got)
+	}
+	data, err := os.ReadFile(c.cfg.EnvFile)
+	if err != nil || !strings.Contains(string(data), "http_proxy=http://127.0.0.1:7890") {
+		t.Errorf("环境变量文件内容为 %q，错误 %v", data, err)
+	}
+
+	// 再次设置只更新地址，不覆盖原备份
+	updated := testSettings
+	updated.Port = 7891
+	if err := c.Enable(updated); err != nil {
+		t.Fatalf("再次 Enable 失败: %v", err)
+	}
+
+	if err := c.Disable(); err != nil {
+		t.Fatalf("Disable 失败: %v", err)
+	}
+	if c.Active() {
+		t.Error("Disable 后应当为未设置")
+	}
+	assertRestored(t, runner, original, c)
+}
+
+func TestEnableRollback(t *testing.T) {
+	dir := t.TempDir()
+	runner := newFakeRunner()
+	original := runner.snapshot()
+	c := newTestController(t, runner, dir)
+
+	// GNOME 和环境变量文件之间的 KDE 在切换代理模式时失败
+	runner.fail = func(name string, args []string) bool {
+		return name == "kwriteconfig6" && args[5] == "ProxyType" && args[6] == "1"
+	}
+
+	err := c.Enable(testSettings)
+	if err == nil || !strings.Contains(err.Error(), BackendKDE) {
+		t.Fatalf("Enable 应当返回 KDE 的错误，实际为 %v", err)
+	}
+	if c.Active() {
+		t.Error("设置失败时不应标记为已设置")
+	}
+	assertRestored(t, runner, original, c)
+}
+
+func TestEnableRollbackFailureKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	runner := newFakeRunner()
+	c := newTestController(t, runner, dir)
+
+	// KDE 设置和恢复都失败，备份保留到下次恢复
+	runner.fail = func(name string, args []string) bool {
+		return name == "kwriteconfig6"
+	}
+	if err := c.Enable(testSettings); err == nil {
+		t.Fatal("Enable 应当失败")
+	}
+	if _, err := os.Stat(c.statePath); err != nil {
+		t.Errorf("恢复失败时应保留备份: %v", err)
+	}
+
+	runner.fail = nil
+	if err := c.Disable(); err != nil {
+		t.Fatalf("Disable 失败: %v", err)
+	}
+	if _, err := os.Stat(c.statePath); !os.IsNotExist(err) {
+		t.Errorf("恢复后备份应当删除: %v", err)
+	}
+}
+
+func TestCrashRecovery(t *testing.T) {
+	dir := t.TempDir()
+	runner := newFakeRunner()
+	original := runner.snapshot()
+
+	if err := newTestController(t, runner, dir).Enable(testSettings); err != nil {
+		t.Fatalf("Enable 失败: %v", err)
+	}
+
+	// 模拟崩溃后重新启动：新的控制器再次设置时沿用磁盘上的备份
+	restarted := newTestController(t, runner, dir)
+	if err := restarted.Enable(testSettings); err != nil {
+		t.Fatalf("重启后 Enable 失败: %v", err)
+	}
+
+	// 再次崩溃后启动时恢复遗留的设置
+	recovered := newTestController(t, runner, dir)
+	if err := recovered.Disable(); err != nil {
+		t.Fatalf("Disable 失败: %v", err)
+	}
+	assertRestored(t, runner, original, recovered)
+
+	// 没有备份时 Disable 不做任何事
+	if err := recovered.Disable(); err != nil {
+		t.Errorf("没有备份时 Disable 失败: %v", err)
+	}
+}
+
+func TestBackendSelection(t *testing.T) {
+	dir := t.TempDir()
+	runner := newFakeRunner()
+	runner.fail = func(name string, args []string) bool {
+		return name == "gsettings"
+	}
+
+	tests := []struct {
+		backends []string
+		want     []string
+		ok       bool
+	}{
+		{nil, []string{BackendKDE, BackendEnv}, true},
+		{[]string{BackendEnv}, []string{BackendEnv}, true},
+		{[]string{BackendGNOME}, nil, false},
+		{[]string{"windows"}, nil, false},
+	}
+	for _, tt := range tests {
+		c := newTestController(t, runner, dir)
+		c.cfg.Backends = tt.backends
+
+		backends, err := c.backends()
+		if (err == nil) != tt.ok {
+			t.Errorf("backends(%v) = %v，期望成功为 %v", tt.backends, err, tt.ok)
+			continue
+		}
+		var names []string
+		for _, b := range backends {
+			names = append(names, b.name())
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("backends(%v) = %v，期望 %v", tt.backends, names, tt.want)
+		}
+	}
+}
+
+// assertRestored 检查设置已恢复为 original，环境变量文件和备份已删除
+func assertRestored(t *testing.T, runner *fakeRunner, original map[string]string, c *Controller) {
+	t.Helper()
+	if len(runner.values) != len(original) {
+		t.Errorf("恢复后的设置为 %v，期望 %v", runner.values, original)
+	}
+	for key, want := range original {
+		if got := runner.values[key]; got != want {
+			t.Errorf("%s 恢复为 %q，期望 %q", key, got, want)
+		}
+	}
+	if _, err := os.Stat(c.cfg.EnvFile); !os.IsNotExist(err) {
+		t.Errorf("环境变量文件应当删除: %v", err)
+	}
+	if _, err := os.Stat(c.statePath); !os.IsNotExist(err) {
+		t.Errorf("备份应当删除: %v", err)
+	}
+}
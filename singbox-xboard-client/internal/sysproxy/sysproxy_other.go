@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sysproxy
+
+// allBackends 目前只支持 Linux 桌面
+func (c *Controller) allBackends() []backend {
+	return nil
+}
@@ -0,0 +1,244 @@
+package traffic
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+const (
+	// breakdownBucket 滑动窗口的最小粒度
+	breakdownBucket = 10 * time.Second
+
+	// MaxBreakdownWindow 排行保留的最长时间窗口
+	MaxBreakdownWindow = time.Hour
+
+	// DefaultBreakdownWindow 未指定窗口时统计最近 10 分钟
+	DefaultBreakdownWindow = 10 * time.Minute
+
+	// DefaultTopLimit 未指定数量时每张排行返回的条数
+	DefaultTopLimit = 10
+)
+
+// finalRule 未记录规则的连接按最终出站处理
+const finalRule = "final"
+
+// Usage 排行中的一项
+type Usage struct {
+	Name string `json:"name"`
+	Counter
+	Total       int64 `json:"total"`
+	Connections int   `json:"connections"` // 窗口内新建的连接数
+}
+
+// Top 时间窗口内按规则、出站和目标域名的流量排行
+type Top struct {
+	Window    float64   `json:"window"` // 窗口时长（秒）
+	Since     time.Time `json:"since"`
+	Rules     []Usage   `json:"rules"`
+	Outbounds []Usage   `json:"outbounds"`
+	Domains   []Usage   `json:"domains"`
+}
+
+// Breakdown 将连接的流量按命中的规则、出站和目标域名归类，保留最近一小时的滑动窗口
+type Breakdown struct {
+	mu      sync.Mutex
+	seen    map[string]Counter // 连接上次记录时的累计流量
+	buckets []*breakdownSlot   // 按时间顺序排列
+}
+
+// breakdownSlot 一个时间片内的分类流量
+type breakdownSlot struct {
+	start     time.Time
+	rules     map[string]*Usage
+	outbounds map[string]*Usage
+	domains   map[string]*Usage
+}
+
+// NewBreakdown 创建流量分类统计
+func NewBreakdown() *Breakdown {
+	return &Breakdown{
+		seen: make(map[string]Counter),
+	}
+}
+
+// Record 将一次连接变化中的增量计入当前时间片
+func (b *Breakdown) Record(update singbox.ConnectionsUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slot := b.slot(time.Now())
+	track := func(conn singbox.Connection, added bool) {
+		previous := b.seen[conn.ID]
+		b.seen[conn.ID] = Counter{Upload: conn.Upload, Download: conn.Download}
+
+		delta := Counter{
+			Upload:   max(conn.Upload-previous.Upload, 0),
+			Download: max(conn.Download-previous.Download, 0),
+		}
+		if delta.Total() == 0 && !added {
+			return
+		}
+
+		connections := 0
+		if added {
+			connections = 1
+		}
+		slot.add(slot.rules, ruleName(conn), delta, connections)
+		if conn.Outbound != "" {
+			slot.add(slot.outbounds, conn.Outbound, delta, connections)
+		}
+		if domain := domainName(conn); domain != "" {
+			slot.add(slot.domains, domain, delta, connections)
+		}
+	}
+
+	for _, conn := range update.Added {
+		track(conn, true)
+	}
+	for _, conn := range update.Updated {
+		track(conn, false)
+	}
+	for _, conn := range update.Closed {
+		track(conn, false)
+		delete(b.seen, conn.ID)
+	}
+}
+
+// Top 汇总最近 window 内的流量，每张排行按总量从大到小取前 limit 项
+func (b *Breakdown) Top(window time.Duration, limit int) Top {
+	if window <= 0 {
+		window = DefaultBreakdownWindow
+	}
+	window = min(window, MaxBreakdownWindow)
+	if limit <= 0 {
+		limit = DefaultTopLimit
+	}
+
+	now := time.Now()
+	since := now.Add(-window)
+
+	rules := make(map[string]*Usage)
+	outbounds := make(map[string]*Usage)
+	domains := make(map[string]*Usage)
+
+	b.mu.Lock()
+	b.prune(now)
+	for _, slot := range b.buckets {
+		// 时间片与窗口部分重叠时整片计入
+		if slot.start.Add(breakdownBucket).Before(since) {
+			continue
+		}
+		merge(rules, slot.rules)
+		merge(outbounds, slot.outbounds)
+		merge(domains, slot.domains)
+	}
+	b.mu.Unlock()
+
+	return Top{
+		Window:    window.Seconds(),
+		Since:     since,
+		Rules:     ranked(rules, limit),
+		Outbounds: ranked(outbounds, limit),
+		Domains:   ranked(domains, limit),
+	}
+}
+
+// slot 返回 now 所在的时间片，必要时新建，调用方需持有 b.mu
+func (b *Breakdown) slot(now time.Time) *breakdownSlot {
+	start := now.Truncate(breakdownBucket)
+	if n := len(b.buckets); n > 0 && b.buckets[n-1].start.Equal(start) {
+		return b.buckets[n-1]
+	}
+
+	b.prune(now)
+	slot := &breakdownSlot{
+		start:     start,
+		rules:     make(map[string]*Usage),
+		outbounds: make(map[string]*Usage),
+		domains:   make(map[string]*Usage),
+	}
+	b.buckets = append(b.buckets, slot)
+	return slot
+}
+
+// prune 丢弃超出最长窗口的时间片，调用方需持有 b.mu
+func (b *Breakdown) prune(now time.Time) {
+	cutoff := now.Add(-MaxBreakdownWindow - breakdownBucket)
+	i := 0
+	for i < len(b.buckets) && b.buckets[i].start.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		b.buckets = append(b.buckets[:0], b.buckets[i:]...)
+	}
+}
+
+func (s *breakdownSlot) add(items map[string]*Usage, name string, delta Counter, connections int) {
+	item := items[name]
+	if item == nil {
+		item = &Usage{Name: name}
+		items[name] = item
+	}
+	item.add(delta)
+	item.Connections += connections
+}
+
+// merge 将时间片中的各项累加到汇总中
+func merge(dst, src map[string]*Usage) {
+	for name, item := range src {
+		total := dst[name]
+		if total == nil {
+			total = &Usage{Name: name}
+			dst[name] = total
+		}
+		total.add(item.Counter)
+		total.Connections += item.Connections
+	}
+}
+
+// ranked 按总量从大到小排序并取前 limit 项
+func ranked(items map[string]*Usage, limit int) []Usage {
+	result := make([]Usage, 0, len(items))
+	for _, item := range items {
+		usage := *item
+		usage.Total = usage.Counter.Total()
+		result = append(result, usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// ruleName 连接命中的规则，如 rule_set=geosite-cn => route(direct)
+func ruleName(conn singbox.Connection) string {
+	switch {
+	case conn.Rule == "":
+		return finalRule
+	case conn.RulePayload != "":
+		return conn.Rule + "(" + conn.RulePayload + ")"
+	default:
+		return conn.Rule
+	}
+}
+
+// domainName 连接的目标域名，没有域名时使用目标 IP
+func domainName(conn singbox.Connection) string {
+	if conn.Host != "" {
+		return conn.Host
+	}
+	if host, _, err := net.SplitHostPort(conn.Destination); err == nil {
+		return host
+	}
+	return conn.Destination
+}
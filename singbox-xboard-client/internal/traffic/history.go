@@ -0,0 +1,152 @@
+package traffic
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 历史查询的分组方式
+const (
+	GroupByNode  = "node"
+	GroupByHour  = "hour"
+	GroupByDay   = "day"
+	GroupByMonth = "month"
+)
+
+// DefaultRange 未指定范围时查询最近 7 天
+const DefaultRange = 7 * 24 * time.Hour
+
+// NodeUsage 节点在查询范围内的流量
+type NodeUsage struct {
+	Node string `json:"node"`
+	Counter
+	Total int64 `json:"total"`
+}
+
+// PeriodUsage 某个时段的流量及各节点明细
+type PeriodUsage struct {
+	Period string `json:"period"`
+	Counter
+	Total int64              `json:"total"`
+	Nodes map[string]Counter `json:"nodes"`
+}
+
+// ParseRange 解析查询范围，支持 Go 时长格式和以 d 结尾的天数，如 24h、7d
+func ParseRange(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultRange, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("范围无效: %s", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("范围无效: %s", value)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("范围无效: %s", value)
+	}
+	return d, nil
+}
+
+// ByNode 按节点汇总 since 之后的流量，按总量从大到小排列
+func (s *Store) ByNode(since time.Time) ([]NodeUsage, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+
+	// 选择仍保留该时间段数据的最细粒度
+	bucket := Monthly
+	for _, p := range periods {
+		if p.retention == 0 || time.Since(since) <= p.retention {
+			bucket = p.bucket
+			break
+		}
+	}
+
+	totals := make(map[string]*Counter)
+	err := s.scan(bucket, since, func(_, node string, counter Counter) {
+		if totals[node] == nil {
+			totals[node] = &Counter{}
+		}
+		totals[node].add(counter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NodeUsage, 0, len(totals))
+	for node, counter := range totals {
+		result = append(result, NodeUsage{Node: node, Counter: *counter, Total: counter.Total()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Node < result[j].Node
+	})
+	return result, nil
+}
+
+// ByPeriod 按小时、天或月汇总 since 之后的流量，按时间顺序排列
+func (s *Store) ByPeriod(groupBy string, since time.Time) ([]PeriodUsage, error) {
+	bucket, ok := map[string]string{
+		GroupByHour:  Hourly,
+		GroupByDay:   Daily,
+		GroupByMonth: Monthly,
+	}[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("分组方式无效: %s", groupBy)
+	}
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+
+	var result []PeriodUsage
+	err := s.scan(bucket, since, func(period, node string, counter Counter) {
+		if len(result) == 0 || result[len(result)-1].Period != period {
+			result = append(result, PeriodUsage{Period: period, Nodes: make(map[string]Counter)})
+		}
+		usage := &result[len(result)-1]
+		usage.add(counter)
+		usage.Total = usage.Counter.Total()
+		usage.Nodes[node] = counter
+	})
+	return result, err
+}
+
+// scan 按时间顺序遍历 since 所在时段及之后的记录
+func (s *Store) scan(bucket string, since time.Time, fn func(period, node string, counter Counter)) error {
+	var layout string
+	for _, p := range periods {
+		if p.bucket == bucket {
+			layout = p.layout
+		}
+	}
+	start := []byte(since.Format(layout))
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucket)).Cursor()
+		for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+			if bytes.IndexByte(k, 0) < 0 {
+				continue
+			}
+			period, node := splitKey(k)
+			fn(period, node, decodeCounter(v))
+		}
+		return nil
+	})
+}
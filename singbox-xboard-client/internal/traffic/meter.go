@@ -0,0 +1,92 @@
+package traffic
+
+import (
+	"sync"
+
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+)
+
+// UnattributedNode 无法归属到出站的流量使用的节点名，见 singbox.ConnectionsUpdate
+const UnattributedNode = "(unattributed)"
+
+// Meter 根据连接的累计流量计算各出站的增量
+type Meter struct {
+	mu      sync.Mutex
+	seen    map[string]Counter  // 连接上次记录时的累计流量
+	pending map[string]*Counter // 尚未取走的出站增量
+}
+
+// NewMeter 创建流量增量计算器
+func NewMeter() *Meter {
+	return &Meter{
+		seen:    make(map[string]Counter),
+		pending: make(map[string]*Counter),
+	}
+}
+
+// Record 累计一次连接变化中的增量，计入连接实际使用的出站
+func (m *Meter) Record(update singbox.ConnectionsUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	track := func(conn singbox.Connection) {
+		previous := m.seen[conn.ID]
+		m.seen[conn.ID] = Counter{Upload: conn.Upload, Download: conn.Download}
+
+		delta := Counter{
+			Upload:   max(conn.Upload-previous.Upload, 0),
+			Download: max(conn.Download-previous.Download, 0),
+		}
+		if conn.Outbound != "" && delta.Total() > 0 {
+			m.add(conn.Outbound, delta)
+		}
+	}
+
+	for _, conn := range update.Added {
+		track(conn)
+	}
+	for _, conn := range update.Updated {
+		track(conn)
+	}
+	for _, conn := range update.Closed {
+		track(conn)
+		delete(m.seen, conn.ID)
+	}
+
+	unattributed := Counter{Upload: update.UnattributedUpload, Download: update.UnattributedDownload}
+	if unattributed.Total() > 0 {
+		m.add(UnattributedNode, unattributed)
+	}
+}
+
+// Take 取走并清空累计的增量
+func (m *Meter) Take() map[string]Counter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]Counter, len(m.pending))
+	for node, counter := range m.pending {
+		result[node] = *counter
+	}
+	m.pending = make(map[string]*Counter)
+	return result
+}
+
+// Restore 放回未能处理的增量
+func (m *Meter) Restore(deltas map[string]Counter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for node, delta := range deltas {
+		m.add(node, delta)
+	}
+}
+
+func (m *Meter) add(node string, delta Counter) {
+	counter := m.pending[node]
+	if counter == nil {
+		counter = &Counter{}
+		m.pending[node] = counter
+	}
+	counter.add(delta)
+}
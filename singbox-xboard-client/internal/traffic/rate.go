@@ -0,0 +1,136 @@
+package traffic
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 速率序列的分辨率
+const (
+	PerSecond = "1s"
+	PerMinute = "1m"
+)
+
+const (
+	// SampleInterval 采样累计流量的间隔，与 sing-box 连接轮询的间隔一致
+	SampleInterval = time.Second
+
+	// staleAfter 超过该时长没有采样时认为速率为 0，如 sing-box 已停止
+	staleAfter = 3 * SampleInterval
+
+	// secondPoints 按秒保留的点数，即最近 5 分钟
+	secondPoints = 300
+
+	// minutePoints 按分钟保留的点数，即最近 24 小时
+	minutePoints = 24 * 60
+)
+
+// Rate 一段时间内的平均速率（字节/秒），Time 为该时段的开始时间
+type Rate struct {
+	Time     time.Time `json:"time"`
+	Upload   int64     `json:"upload"`
+	Download int64     `json:"download"`
+}
+
+// Series 根据累计流量计算实时速率，并保留按秒和按分钟降采样的序列
+type Series struct {
+	mu       sync.Mutex
+	last     Counter   // 上次采样时的累计流量
+	lastTime time.Time // 上次采样时间，为零表示尚未采样
+	current  Rate
+
+	seconds []Rate
+	minutes []Rate
+
+	minute      time.Time // 正在累计的分钟
+	minuteBytes Counter   // 该分钟内的流量
+}
+
+// NewSeries 创建速率序列
+func NewSeries() *Series {
+	return &Series{}
+}
+
+// Sample 记录一次累计流量并返回自上次采样以来的速率。
+// 累计值变小说明 sing-box 已重启，此时将新的累计值视为增量
+func (s *Series) Sample(total Counter, at time.Time) Rate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastTime.IsZero() {
+		s.last, s.lastTime = total, at
+		s.minute = at.Truncate(time.Minute)
+		return s.current
+	}
+
+	delta := Counter{
+		Upload:   total.Upload - s.last.Upload,
+		Download: total.Download - s.last.Download,
+	}
+	if delta.Upload < 0 || delta.Download < 0 {
+		delta = total
+	}
+	elapsed := at.Sub(s.lastTime).Seconds()
+	s.last, s.lastTime = total, at
+	if elapsed <= 0 {
+		return s.current
+	}
+
+	s.current = Rate{
+		Time:     at.Add(-SampleInterval).Truncate(time.Second),
+		Upload:   int64(float64(delta.Upload) / elapsed),
+		Download: int64(float64(delta.Download) / elapsed),
+	}
+	s.seconds = appendPoint(s.seconds, s.current, secondPoints)
+
+	// 进入新的一分钟时写入上一分钟的平均速率
+	if minute := at.Truncate(time.Minute); minute.After(s.minute) {
+		seconds := int64(time.Minute / time.Second)
+		s.minutes = appendPoint(s.minutes, Rate{
+			Time:     s.minute,
+			Upload:   s.minuteBytes.Upload / seconds,
+			Download: s.minuteBytes.Download / seconds,
+		}, minutePoints)
+		s.minute = minute
+		s.minuteBytes = Counter{}
+	}
+	s.minuteBytes.add(delta)
+	return s.current
+}
+
+// Current 返回最近一次采样的速率，长时间没有采样时返回 0
+func (s *Series) Current() Rate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastTime) > staleAfter {
+		return Rate{}
+	}
+	return s.current
+}
+
+// Query 返回指定分辨率的序列，按时间从旧到新排列
+func (s *Series) Query(resolution string) ([]Rate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var points []Rate
+	switch resolution {
+	case PerSecond:
+		points = s.seconds
+	case PerMinute:
+		points = s.minutes
+	default:
+		return nil, fmt.Errorf("分辨率可选 %s、%s", PerSecond, PerMinute)
+	}
+	return append([]Rate{}, points...), nil
+}
+
+// appendPoint 追加一个点，超出上限时丢弃最旧的点
+func appendPoint(points []Rate, point Rate, limit int) []Rate {
+	if len(points) >= limit {
+		copy(points, points[len(points)-limit+1:])
+		points = points[:limit-1]
+	}
+	return append(points, point)
+}
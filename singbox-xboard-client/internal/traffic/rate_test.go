@@ -0,0 +1,127 @@
+package traffic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesRate(t *testing.T) {
+	s := NewSeries()
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	if rate := s.Sample(Counter{Upload: 1000, Download: 5000}, start); rate != (Rate{}) {
+		t.Errorf("首次采样的速率为 %+v，期望为 0", rate)
+	}
+
+	// 轮询间隔不均匀时按实际间隔计算，恒定流量的速率不抖动
+	total := Counter{Upload: 1000, Download: 5000}
+	at := start
+	for _, gap := range []time.Duration{1500 * time.Millisecond, 500 * time.Millisecond, time.Second} {
+		at = at.Add(gap)
+		total.Upload += int64(100 * gap.Seconds())
+		total.Download += int64(1000 * gap.Seconds())
+		rate := s.Sample(total, at)
+		if rate.Upload != 100 || rate.Download != 1000 {
+			t.Errorf("间隔 %v 的速率为 %d/%d，期望 100/1000", gap, rate.Upload, rate.Download)
+		}
+	}
+
+	// 同一时间重复采样不改变速率
+	if rate := s.Sample(total, at); rate.Upload != 100 {
+		t.Errorf("重复采样的速率为 %+v", rate)
+	}
+
+	// 累计值变小说明 sing-box 已重启，新的累计值即为增量
+	at = at.Add(time.Second)
+	if rate := s.Sample(Counter{Upload: 50, Download: 70}, at); rate.Upload != 50 || rate.Download != 70 {
+		t.Errorf("重启后的速率为 %d/%d，期望 50/70", rate.Upload, rate.Download)
+	}
+
+	points, err := s.Query(PerSecond)
+	if err != nil || len(points) != 4 {
+		t.Errorf("Query(1s) = %d 个点, %v，期望 4 个点", len(points), err)
+	}
+	if _, err := s.Query("5m"); err == nil {
+		t.Error("Query 应当拒绝无效的分辨率")
+	}
+}
+
+func TestSeriesDownsample(t *testing.T) {
+	s := NewSeries()
+	minute := time.Now().Add(-time.Hour).Truncate(time.Minute)
+
+	// 第一分钟每秒上传 60 字节，共 60 秒；第二分钟每秒 120 字节
+	var total Counter
+	s.Sample(total, minute)
+	for i := 1; i <= 120; i++ {
+		if i <= 60 {
+			total.Upload += 60
+		} else {
+			total.Upload += 120
+		}
+		s.Sample(total, minute.Add(time.Duration(i)*time.Second))
+	}
+	// 进入第三分钟后写入第二分钟
+	total.Upload += 10
+	s.Sample(total, minute.Add(121*time.Second))
+
+	points, err := s.Query(PerMinute)
+	if err != nil {
+		t.Fatalf("Query(1m) 失败: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("按分钟的点数为 %d，期望 2: %+v", len(points), points)
+	}
+	// 第 60 秒的采样属于下一分钟，因此第一分钟只有 59 秒的流量
+	want := []Rate{
+		{Time: minute, Upload: 59 * 60 / 60},
+		{Time: minute.Add(time.Minute), Upload: (60 + 59*120) / 60},
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("第 %d 分钟为 %+v，期望 %+v", i+1, points[i], want[i])
+		}
+	}
+}
+
+func TestSeriesLimit(t *testing.T) {
+	s := NewSeries()
+	start := time.Now().Add(-time.Hour)
+
+	var total Counter
+	for i := 0; i <= secondPoints+100; i++ {
+		total.Download += int64(i)
+		s.Sample(total, start.Add(time.Duration(i)*time.Second))
+	}
+
+	points, _ := s.Query(PerSecond)
+	if len(points) != secondPoints {
+		t.Fatalf("按秒的点数为 %d，期望 %d", len(points), secondPoints)
+	}
+	// 丢弃最旧的点，最新的点在最后
+	if last := points[len(points)-1]; last.Download != secondPoints+100 {
+		t.Errorf("最后一个点为 %+v，期望下载速率 %d", last, secondPoints+100)
+	}
+	if first := points[0]; first.Download != 101 {
+		t.Errorf("第一个点为 %+v，期望下载速率 101", first)
+	}
+}
+
+func TestSeriesStale(t *testing.T) {
+	s := NewSeries()
+	now := time.Now()
+
+	s.Sample(Counter{}, now.Add(-2*time.Second))
+	s.Sample(Counter{Upload: 500}, now.Add(-time.Second))
+	if rate := s.Current(); rate.Upload != 500 {
+		t.Errorf("Current() = %+v，期望上传速率 500", rate)
+	}
+
+	// sing-box 停止后不再轮询，速率归零
+	s = NewSeries()
+	s.Sample(Counter{}, now.Add(-10*time.Second))
+	s.Sample(Counter{Upload: 500}, now.Add(-9*time.Second))
+	if rate := s.Current(); rate != (Rate{}) {
+		t.Errorf("长时间未采样时 Current() = %+v，期望为 0", rate)
+	}
+}
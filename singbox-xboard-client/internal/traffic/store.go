@@ -0,0 +1,245 @@
+// Package traffic 按出站节点持久化流量，并按小时、天、月汇总供历史查询
+package traffic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 汇总粒度
+const (
+	Hourly  = "hourly"
+	Daily   = "daily"
+	Monthly = "monthly"
+)
+
+const (
+	// flushInterval 内存中的增量写入数据库的间隔
+	flushInterval = time.Minute
+
+	// pruneInterval 清理过期数据的间隔
+	pruneInterval = time.Hour
+)
+
+// periods 各汇总粒度的键格式和保留时长，0 表示永久保留
+var periods = []struct {
+	bucket    string
+	layout    string
+	retention time.Duration
+}{
+	{Hourly, "2006-01-02T15", 31 * 24 * time.Hour},
+	{Daily, "2006-01-02", 400 * 24 * time.Hour},
+	{Monthly, "2006-01", 0},
+}
+
+// Counter 上传和下载字节数
+type Counter struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+}
+
+// Total 上传与下载之和
+func (c Counter) Total() int64 {
+	return c.Upload + c.Download
+}
+
+func (c *Counter) add(other Counter) {
+	c.Upload += other.Upload
+	c.Download += other.Download
+}
+
+// Store 流量数据库，记录的增量先在内存中累计，定期写入磁盘
+type Store struct {
+	db     *bolt.DB
+	meter  *Meter
+	logger *logrus.Logger
+
+	mu        sync.Mutex
+	lastPrune time.Time
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// Open 打开流量数据库，path 为空时使用配置目录下的 traffic.db
+func Open(path string) (*Store, error) {
+	if path == "" {
+		path = filepath.Join(config.GetConfigDir(), "traffic.db")
+	}
+
+	// 另一个实例占用数据库时不无限等待
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开流量数据库失败: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, p := range periods {
+			if _, err := tx.CreateBucketIfNotExists([]byte(p.bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化流量数据库失败: %w", err)
+	}
+
+	return &Store{
+		db:     db,
+		meter:  NewMeter(),
+		logger: logrus.New(),
+	}, nil
+}
+
+// SetLogger 设置日志记录器
+func (s *Store) SetLogger(logger *logrus.Logger) {
+	s.logger = logger
+}
+
+// Start 开始定期写入，Close 时停止
+func (s *Store) Start() {
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(s.doneCh)
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					s.logger.Warnf("写入流量数据失败: %v", err)
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Close 写入剩余增量并关闭数据库
+func (s *Store) Close() error {
+	if s.stopCh != nil {
+		close(s.stopCh)
+		<-s.doneCh
+	}
+	if err := s.Flush(); err != nil {
+		s.logger.Warnf("写入流量数据失败: %v", err)
+	}
+	return s.db.Close()
+}
+
+// Record 记录一次连接变化
+func (s *Store) Record(update singbox.ConnectionsUpdate) {
+	s.meter.Record(update)
+}
+
+// Flush 将内存中的增量计入当前时段的各级汇总
+func (s *Store) Flush() error {
+	pending := s.meter.Take()
+
+	s.mu.Lock()
+	prune := time.Since(s.lastPrune) >= pruneInterval
+	s.mu.Unlock()
+
+	now := time.Now()
+	if len(pending) > 0 {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for _, p := range periods {
+				bucket := tx.Bucket([]byte(p.bucket))
+				period := now.Format(p.layout)
+				for node, delta := range pending {
+					key := entryKey(period, node)
+					counter := decodeCounter(bucket.Get(key))
+					counter.add(delta)
+					if err := bucket.Put(key, encodeCounter(counter)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// 写入失败时放回，下次再试
+			s.meter.Restore(pending)
+			return err
+		}
+	}
+
+	if prune {
+		s.mu.Lock()
+		s.lastPrune = now
+		s.mu.Unlock()
+		return s.prune(now)
+	}
+	return nil
+}
+
+// prune 删除超过保留时长的汇总
+func (s *Store) prune(now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, p := range periods {
+			if p.retention == 0 {
+				continue
+			}
+			cutoff := []byte(now.Add(-p.retention).Format(p.layout))
+			bucket := tx.Bucket([]byte(p.bucket))
+
+			var expired [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			for _, k := range expired {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// entryKey 时段和节点组成的键，时段在前以便按时间范围遍历
+func entryKey(period, node string) []byte {
+	return []byte(period + "\x00" + node)
+}
+
+// splitKey 拆分键中的时段和节点
+func splitKey(key []byte) (period, node string) {
+	i := bytes.IndexByte(key, 0)
+	if i < 0 {
+		return string(key), ""
+	}
+	return string(key[:i]), string(key[i+1:])
+}
+
+func encodeCounter(c Counter) []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[:8], uint64(c.Upload))
+	binary.BigEndian.PutUint64(buf[8:], uint64(c.Download))
+	return buf
+}
+
+func decodeCounter(data []byte) Counter {
+	if len(data) != 16 {
+		return Counter{}
+	}
+	return Counter{
+		Upload:   int64(binary.BigEndian.Uint64(data[:8])),
+		Download: int64(binary.BigEndian.Uint64(data[8:])),
+	}
+}
@@ -0,0 +1,174 @@
+package traffic
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/your-username/singbox-xboard-client/internal/singbox"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "traffic.db"))
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestMeter(t *testing.T) {
+	m := NewMeter()
+	m.Record(singbox.ConnectionsUpdate{
+		Added: []singbox.Connection{
+			{ID: "a", Outbound: "hk", Upload: 100, Download: 1000},
+			{ID: "b", Outbound: "", Upload: 50, Download: 50}, // 无出站的连接不计入
+		},
+	})
+	m.Record(singbox.ConnectionsUpdate{
+		Updated: []singbox.Connection{{ID: "a", Outbound: "hk", Upload: 150, Download: 1500}},
+		Closed:  []singbox.Connection{{ID: "c", Outbound: "jp", Upload: 10, Download: 20}},
+	})
+	m.Record(singbox.ConnectionsUpdate{
+		Closed:               []singbox.Connection{{ID: "a", Outbound: "hk", Upload: 160, Download: 1600}},
+		UnattributedUpload:   5,
+		UnattributedDownload: 7,
+	})
+
+	got := m.Take()
+	want := map[string]Counter{
+		"hk":             {Upload: 160, Download: 1600},
+		"jp":             {Upload: 10, Download: 20},
+		UnattributedNode: {Upload: 5, Download: 7},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Take() = %v，期望 %v", got, want)
+	}
+	for node, counter := range want {
+		if got[node] != counter {
+			t.Errorf("%s 的增量为 %v，期望 %v", node, got[node], counter)
+		}
+	}
+	if pending := m.Take(); len(pending) != 0 {
+		t.Errorf("再次 Take() = %v，期望为空", pending)
+	}
+
+	// 放回的增量与新的增量合并
+	m.Restore(map[string]Counter{"hk": {Upload: 1, Download: 1}})
+	m.Record(singbox.ConnectionsUpdate{
+		Added: []singbox.Connection{{ID: "d", Outbound: "hk", Upload: 2, Download: 3}},
+	})
+	if got := m.Take()["hk"]; got != (Counter{Upload: 3, Download: 4}) {
+		t.Errorf("放回后 hk 的增量为 %v，期望 {3 4}", got)
+	}
+}
+
+func TestStoreRollup(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		s.Record(singbox.ConnectionsUpdate{
+			Closed: []singbox.Connection{
+				{ID: string(rune('a' + i)), Outbound: "hk", Upload: 100, Download: 200},
+				{ID: string(rune('x' + i)), Outbound: "jp", Upload: 1, Download: 2},
+			},
+		})
+		if err := s.Flush(); err != nil {
+			t.Fatalf("Flush 失败: %v", err)
+		}
+	}
+
+	for _, groupBy := range []string{GroupByHour, GroupByDay, GroupByMonth} {
+		usage, err := s.ByPeriod(groupBy, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("ByPeriod(%s) 失败: %v", groupBy, err)
+		}
+		if len(usage) == 0 {
+			t.Fatalf("ByPeriod(%s) 没有数据", groupBy)
+		}
+		last := usage[len(usage)-1]
+		if last.Nodes["hk"] != (Counter{Upload: 200, Download: 400}) {
+			t.Errorf("ByPeriod(%s) hk = %v，期望 {200 400}", groupBy, last.Nodes["hk"])
+		}
+		if last.Total != 606 {
+			t.Errorf("ByPeriod(%s) 总量为 %d，期望 606", groupBy, last.Total)
+		}
+	}
+
+	nodes, err := s.ByNode(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ByNode 失败: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0].Node != "hk" || nodes[0].Total != 600 || nodes[1].Node != "jp" {
+		t.Errorf("ByNode = %+v，期望 hk 600 在前、jp 在后", nodes)
+	}
+
+	if _, err := s.ByPeriod("week", now); err == nil {
+		t.Error("ByPeriod 应当拒绝无效的分组方式")
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	s := openTestStore(t)
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	entries := map[string][]time.Time{
+		Hourly:  {now.Add(-32 * 24 * time.Hour), now.Add(-24 * time.Hour)},
+		Daily:   {now.Add(-401 * 24 * time.Hour), now.Add(-30 * 24 * time.Hour)},
+		Monthly: {now.AddDate(-5, 0, 0), now},
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, p := range periods {
+			bucket := tx.Bucket([]byte(p.bucket))
+			for _, at := range entries[p.bucket] {
+				key := entryKey(at.Format(p.layout), "hk")
+				if err := bucket.Put(key, encodeCounter(Counter{Upload: 1})); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("写入测试数据失败: %v", err)
+	}
+
+	if err := s.prune(now); err != nil {
+		t.Fatalf("prune 失败: %v", err)
+	}
+
+	// 小时和天只保留未过期的一条，月份永久保留
+	want := map[string]int{Hourly: 1, Daily: 1, Monthly: 2}
+	s.db.View(func(tx *bolt.Tx) error {
+		for bucket, n := range want {
+			if got := tx.Bucket([]byte(bucket)).Stats().KeyN; got != n {
+				t.Errorf("%s 剩余 %d 条，期望 %d", bucket, got, n)
+			}
+		}
+		return nil
+	})
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", DefaultRange, true},
+		{"24h", 24 * time.Hour, true},
+		{"30d", 30 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"week", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRange(%q) = %v, %v，期望 %v", tt.value, got, err, tt.want)
+		}
+	}
+}
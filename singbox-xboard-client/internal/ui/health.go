@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/clashapi"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/health"
+	"github.com/your-username/singbox-xboard-client/internal/render"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
+)
+
+// healthProber 通过 Clash API 测试订阅节点
+type healthProber struct {
+	s *Server
+}
+
+func (p healthProber) IsRunning() bool {
+	return p.s.sbManager.IsRunning()
+}
+
+func (p healthProber) NodeTags() ([]string, error) {
+	return p.s.subManager.NodeTags()
+}
+
+// TestDelay 只有 Clash API 报告测速超时或失败时才算节点失败
+func (p healthProber) TestDelay(node, testURL string, timeout time.Duration) (int, error) {
+	delay, err := p.s.sbManager.TestDelay(node, testURL, timeout)
+	var status *clashapi.StatusError
+	if errors.As(err, &status) && (status.Code == http.StatusGatewayTimeout || status.Code == http.StatusServiceUnavailable) {
+		return 0, fmt.Errorf("%w: %v", health.ErrNodeFailed, err)
+	}
+	return delay, err
+}
+
+// setupHealth 按配置启动或停止节点健康检查，并按评分重新计算排除的节点
+func (s *Server) setupHealth(cfg *config.Config) {
+	if previous := s.healthMon.Swap(nil); previous != nil {
+		previous.Stop()
+	}
+	s.updateExcluded(cfg.Health.MinScore)
+	if !cfg.Health.Enabled {
+		return
+	}
+
+	next := health.NewMonitor(s.health, healthProber{s}, cfg.Health)
+	next.SetLogger(s.logger)
+	next.OnRound(func() {
+		s.updateExcluded(cfg.Health.MinScore)
+	})
+	next.Start()
+	s.healthMon.Store(next)
+}
+
+// updateExcluded 将评分过低的节点移出 urltest 组，排除的节点变化时重新生成配置
+func (s *Server) updateExcluded(minScore int) {
+	s.healthMu.Lock()
+	excluded := s.health.Excluded(minScore, s.excluded)
+	s.excluded = excluded
+	s.healthMu.Unlock()
+
+	if !s.subManager.SetExcludedNodes(excluded) {
+		return
+	}
+	s.logger.Infof("自动测速排除 %d 个低评分节点", len(excluded))
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
+}
+
+// nodeItems 为节点列表附加出站标签和健康状况
+func (s *Server) nodeItems(nodes []xboard.NodeInfo) []health.Candidate {
+	tags := make(map[int]string)
+	if ids, err := s.subManager.NodeIDs(); err == nil {
+		for tag, id := range ids {
+			tags[id] = tag
+		}
+	}
+
+	s.healthMu.Lock()
+	excluded := s.excluded
+	s.healthMu.Unlock()
+
+	items := make([]health.Candidate, 0, len(nodes))
+	for _, node := range nodes {
+		item := health.Candidate{NodeInfo: node, Tag: tags[node.ID]}
+		if item.Tag != "" {
+			item.Excluded = excluded[item.Tag]
+			if stats, ok := s.health.Stats(item.Tag); ok {
+				item.Health = &stats
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// smartSource 智能选择使用的节点数据和 select 组
+type smartSource struct {
+	s *Server
+}
+
+func (p smartSource) IsRunning() bool {
+	return p.s.sbManager.IsRunning()
+}
+
+func (p smartSource) Candidates() ([]health.Candidate, error) {
+	nodes, err := p.s.subManager.GetNodeList()
+	if err != nil {
+		return nil, err
+	}
+	return p.s.nodeItems(nodes), nil
+}
+
+func (p smartSource) Current() (string, error) {
+	return p.s.sbManager.SelectedOutbound(render.TagSelect)
+}
+
+func (p smartSource) Select(tag string) error {
+	return p.s.sbManager.SelectOutbound(render.TagSelect, tag)
+}
+
+// setupSmart 按配置启动或停止智能选择节点
+func (s *Server) setupSmart(cfg *config.Config) {
+	if previous := s.smart.Swap(nil); previous != nil {
+		previous.Stop()
+	}
+	if !cfg.Smart.Enabled {
+		return
+	}
+
+	next := health.NewSmart(smartSource{s}, cfg.Smart)
+	next.SetLogger(s.logger)
+	next.OnSwitch(func(decision health.Decision) {
+		s.hub.broadcast(map[string]interface{}{
+			"type":     "smart",
+			"decision": decision,
+		})
+	})
+	next.Start()
+	s.smart.Store(next)
+}
+
+// handleGetSmart 获取智能选择的节点排名和最近一次切换，未启用时按当前数据计算排名
+func (s *Server) handleGetSmart(c *gin.Context) {
+	if smart := s.smart.Load(); smart != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    smart.Status(),
+		})
+		return
+	}
+
+	candidates, err := smartSource{s}.Candidates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": health.SmartStatus{
+			Ranking: health.Rank(candidates, s.currentConfig().Smart.Regions),
+		},
+	})
+}
+
+// handleRunSmart 立即重新选择节点
+func (s *Server) handleRunSmart(c *gin.Context) {
+	smart := s.smart.Load()
+	if smart == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "未启用智能选择节点",
+		})
+		return
+	}
+	if !s.sbManager.IsRunning() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "sing-box 未运行",
+		})
+		return
+	}
+
+	decision, err := smart.Run()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"switched": decision != nil,
+			"decision": decision,
+			"status":   smart.Status(),
+		},
+	})
+}
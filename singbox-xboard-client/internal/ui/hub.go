@@ -0,0 +1,55 @@
+package ui
+
+import "sync"
+
+// wsClientBuffer 每个 WebSocket 客户端的待发送消息上限
+const wsClientBuffer = 64
+
+// wsHub 管理 WebSocket 客户端并广播事件
+type wsHub struct {
+	mu      sync.RWMutex
+	clients map[*wsClient]struct{}
+}
+
+// wsClient 单个 WebSocket 客户端的发送队列
+type wsClient struct {
+	send chan interface{}
+}
+
+// newWSHub 创建广播中心
+func newWSHub() *wsHub {
+	return &wsHub{
+		clients: make(map[*wsClient]struct{}),
+	}
+}
+
+// register 注册客户端
+func (h *wsHub) register() *wsClient {
+	client := &wsClient{send: make(chan interface{}, wsClientBuffer)}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	return client
+}
+
+// unregister 注销客户端
+func (h *wsHub) unregister(client *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+}
+
+// broadcast 向所有客户端发送消息，队列已满的客户端丢弃本条消息
+func (h *wsHub) broadcast(message interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		select {
+		case client.send <- message:
+		default:
+		}
+	}
+}
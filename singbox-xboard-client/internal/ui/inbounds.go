@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// handleGetInbounds 获取入站配置，认证密码以 config.RedactedPassword 代替
+func (s *Server) handleGetInbounds(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config.RedactPasswords(s.currentConfig().Singbox.Inbounds),
+	})
+}
+
+// handleUpdateInbounds 替换全部入站配置，未修改的隐藏密码保留原值
+func (s *Server) handleUpdateInbounds(c *gin.Context) {
+	var inbounds []config.InboundConfig
+	if err := c.ShouldBindJSON(&inbounds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	s.updateInbounds(c, func(previous []config.InboundConfig) ([]config.InboundConfig, error) {
+		return inbounds, config.RestorePasswords(inbounds, previous)
+	})
+}
+
+// handleSetLAN 允许或禁止局域网连接 mixed 入站
+func (s *Server) handleSetLAN(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	s.updateInbounds(c, func(inbounds []config.InboundConfig) ([]config.InboundConfig, error) {
+		candidate := config.Config{}
+		candidate.Singbox.Inbounds = append([]config.InboundConfig{}, inbounds...)
+		err := candidate.SetLANEnabled(*req.Enabled)
+		return candidate.Singbox.Inbounds, err
+	})
+}
+
+// updateInbounds 校验并保存修改后的入站，入站变化会触发 sing-box 完整重启
+func (s *Server) updateInbounds(c *gin.Context, modify func([]config.InboundConfig) ([]config.InboundConfig, error)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	inbounds, err := modify(s.config.Singbox.Inbounds)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	candidate := *s.config
+	candidate.Singbox.Inbounds = inbounds
+	if err := s.subManager.Check(&candidate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := s.saveConfig(&candidate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config.RedactPasswords(inbounds),
+	})
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/metrics"
+)
+
+// setupMetrics 创建指标集合，配置了单独的监听地址时另起 HTTP 服务
+func (s *Server) setupMetrics(cfg *config.Config) {
+	s.metrics = metrics.New(s.sbManager, s.subManager)
+	if !cfg.Metrics.Enabled || cfg.Metrics.Listen == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.Handler())
+	go func() {
+		s.logger.Infof("启动指标服务: http://%s/metrics", cfg.Metrics.Listen)
+		if err := http.ListenAndServe(cfg.Metrics.Listen, mux); err != nil {
+			s.logger.Errorf("指标服务退出: %v", err)
+		}
+	}()
+}
+
+// handleMetrics 在 Web UI 上提供 /metrics，配置了单独的监听地址时不提供
+func (s *Server) handleMetrics(c *gin.Context) {
+	if s.metrics == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if cfg := s.currentConfig(); !cfg.Metrics.Enabled || cfg.Metrics.Listen != "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	s.metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
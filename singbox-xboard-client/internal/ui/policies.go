@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/policy"
+)
+
+// policyActions 策略动作的实现，与对应的界面操作效果相同
+type policyActions struct {
+	s *Server
+}
+
+// SetMode 切换规则模式并保存到应用配置
+func (a policyActions) SetMode(mode string) error {
+	return a.s.setMode(mode)
+}
+
+// SelectOutbound 在 selector 出站组中选择出站
+func (a policyActions) SelectOutbound(selector, outbound string) error {
+	return a.s.sbManager.SelectOutbound(selector, outbound)
+}
+
+// SetRuleSets 启用和停用配置中的规则集，保存后重新生成 sing-box 配置
+func (a policyActions) SetRuleSets(enable, disable []string) error {
+	s := a.s
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	sets := append([]config.RuleSet(nil), s.config.Singbox.Route.RuleSet...)
+	index := make(map[string]int, len(sets))
+	for i, set := range sets {
+		index[set.Tag] = i
+	}
+	for _, group := range []struct {
+		tags     []string
+		disabled bool
+	}{{enable, false}, {disable, true}} {
+		for _, tag := range group.tags {
+			i, ok := index[tag]
+			if !ok {
+				return fmt.Errorf("规则集不存在或为内置规则集: %s", tag)
+			}
+			sets[i].Disabled = group.disabled
+		}
+	}
+
+	candidate := *s.config
+	candidate.Singbox.Route.RuleSet = sets
+	if err := s.subManager.Check(&candidate); err != nil {
+		return err
+	}
+
+	if err := s.saveConfig(&candidate); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	return s.subManager.Rerender()
+}
+
+// setupPolicies 创建策略引擎并按配置安排定时任务
+func (s *Server) setupPolicies(cfg *config.Config) {
+	s.policies = policy.NewEngine(policyActions{s})
+	s.policies.SetLogger(s.logger)
+	s.policies.OnEvent(func(event policy.Event) {
+		s.hub.broadcast(map[string]interface{}{
+			"type":  "policy",
+			"event": event,
+		})
+	})
+	if err := s.policies.Load(cfg.Policies); err != nil {
+		s.logger.Warnf("加载定时策略失败: %v", err)
+	}
+}
+
+// handleGetPolicies 获取定时策略及下次执行时间
+func (s *Server) handleGetPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.policies.List(),
+	})
+}
+
+// handleRunPolicy 立即执行指定策略
+func (s *Server) handleRunPolicy(c *gin.Context) {
+	event, err := s.policies.Run(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if event.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   event.Error,
+			"data":    event,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    event,
+	})
+}
+
+// handleGetPolicyEvents 获取最近的策略执行记录
+func (s *Server) handleGetPolicyEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.policies.Events(),
+	})
+}
@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/config"
+)
+
+// handleGetRules 获取自定义路由规则，按匹配顺序排列
+func (s *Server) handleGetRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.currentConfig().Singbox.Route.Rules,
+	})
+}
+
+// handleAddRule 添加规则，可用 index 参数指定插入位置，默认追加到末尾
+func (s *Server) handleAddRule(c *gin.Context) {
+	var rule config.RouteRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	s.updateRules(c, func(rules []config.RouteRule) ([]config.RouteRule, error) {
+		index := len(rules)
+		if value := c.Query("index"); value != "" {
+			i, err := strconv.Atoi(value)
+			if err != nil || i < 0 || i > len(rules) {
+				return nil, fmt.Errorf("插入位置无效: %s", value)
+			}
+			index = i
+		}
+
+		result := make([]config.RouteRule, 0, len(rules)+1)
+		result = append(result, rules[:index]...)
+		result = append(result, rule)
+		return append(result, rules[index:]...), nil
+	})
+}
+
+// handleUpdateRule 替换指定位置的规则
+func (s *Server) handleUpdateRule(c *gin.Context) {
+	var rule config.RouteRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	s.updateRules(c, func(rules []config.RouteRule) ([]config.RouteRule, error) {
+		index, err := ruleIndex(c.Param("index"), len(rules))
+		if err != nil {
+			return nil, err
+		}
+
+		result := append([]config.RouteRule{}, rules...)
+		result[index] = rule
+		return result, nil
+	})
+}
+
+// handleDeleteRule 删除指定位置的规则
+func (s *Server) handleDeleteRule(c *gin.Context) {
+	s.updateRules(c, func(rules []config.RouteRule) ([]config.RouteRule, error) {
+		index, err := ruleIndex(c.Param("index"), len(rules))
+		if err != nil {
+			return nil, err
+		}
+
+		result := append([]config.RouteRule{}, rules[:index]...)
+		return append(result, rules[index+1:]...), nil
+	})
+}
+
+// handleMoveRule 调整规则顺序，请求体 to 为移动后的位置
+func (s *Server) handleMoveRule(c *gin.Context) {
+	var req struct {
+		To *int `json:"to" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	s.updateRules(c, func(rules []config.RouteRule) ([]config.RouteRule, error) {
+		from, err := ruleIndex(c.Param("index"), len(rules))
+		if err != nil {
+			return nil, err
+		}
+		to := *req.To
+		if to < 0 || to >= len(rules) {
+			return nil, fmt.Errorf("目标位置无效: %d", to)
+		}
+
+		rule := rules[from]
+		result := append([]config.RouteRule{}, rules[:from]...)
+		result = append(result, rules[from+1:]...)
+		result = append(result[:to], append([]config.RouteRule{rule}, result[to:]...)...)
+		return result, nil
+	})
+}
+
+// updateRules 修改规则列表，校验通过后保存配置并重新生成 sing-box 配置
+func (s *Server) updateRules(c *gin.Context, modify func([]config.RouteRule) ([]config.RouteRule, error)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	rules, err := modify(s.config.Singbox.Route.Rules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 先用副本校验出站和规则集引用
+	candidate := *s.config
+	candidate.Singbox.Route.Rules = rules
+	if err := s.subManager.Check(&candidate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := s.saveConfig(&candidate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+	})
+}
+
+// ruleIndex 解析并检查规则位置
+func ruleIndex(value string, count int) (int, error) {
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 || index >= count {
+		return 0, fmt.Errorf("规则位置无效: %s", value)
+	}
+	return index, nil
+}
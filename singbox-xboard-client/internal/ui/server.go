@@ -4,16 +4,32 @@ import (
 	"embed"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+	"github.com/your-username/singbox-xboard-client/internal/alert"
+	"github.com/your-username/singbox-xboard-client/internal/audit"
 	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/health"
+	"github.com/your-username/singbox-xboard-client/internal/metrics"
+	"github.com/your-username/singbox-xboard-client/internal/policy"
+	"github.com/your-username/singbox-xboard-client/internal/reporter"
 	"github.com/your-username/singbox-xboard-client/internal/singbox"
 	"github.com/your-username/singbox-xboard-client/internal/subscription"
+	"github.com/your-username/singbox-xboard-client/internal/sysproxy"
+	"github.com/your-username/singbox-xboard-client/internal/traffic"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
 )
 
 //go:embed static/*
@@ -25,8 +41,24 @@ type Server struct {
 	engine      *gin.Engine
 	subManager  *subscription.Manager
 	sbManager   *singbox.Manager
+	sysProxy    *sysproxy.Controller
+	traffic     *traffic.Store
+	breakdown   *traffic.Breakdown
+	series      *traffic.Series
+	reporter    atomic.Pointer[reporter.Reporter] // 未启用流量上报时为空
+	audit       atomic.Pointer[audit.Logger]      // 未启用审计日志时为空
+	health      *health.Tracker
+	healthMon   atomic.Pointer[health.Monitor]    // 未启用健康检查时为空
+	smart       atomic.Pointer[health.Smart]      // 未启用智能选择节点时为空
+	healthMu    sync.Mutex
+	excluded    map[string]bool // 因评分过低不参与自动测速选择的节点
+	alerts      *alert.Watcher
+	metrics     *metrics.Metrics
+	policies    *policy.Engine
 	logger      *logrus.Logger
 	upgrader    websocket.Upgrader
+	hub         *wsHub
+	configMu    sync.Mutex // 串行化对应用配置的局部修改
 }
 
 // NewServer 创建 UI 服务器
@@ -35,8 +67,11 @@ func NewServer() *Server {
 	gin.SetMode(gin.ReleaseMode)
 
 	s := &Server{
-		engine: gin.New(),
-		logger: logrus.New(),
+		engine:    gin.New(),
+		logger:    logrus.New(),
+		hub:       newWSHub(),
+		breakdown: traffic.NewBreakdown(),
+		series:    traffic.NewSeries(),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // 允许所有来源，生产环境应该限制
@@ -65,12 +100,95 @@ func (s *Server) Start() error {
 	
 	s.sbManager = singbox.NewManager(cfg)
 	s.sbManager.SetLogger(s.logger)
+	
+	// 恢复上次异常退出时遗留的系统代理设置
+	s.sysProxy = sysproxy.NewController(cfg.SystemProxy)
+	s.sysProxy.SetLogger(s.logger)
+	if err := s.sysProxy.Disable(); err != nil {
+		s.logger.Warnf("恢复系统代理设置失败: %v", err)
+	}
+	
+	// 持久化各节点流量
+	if store, err := traffic.Open(""); err != nil {
+		s.logger.Warnf("打开流量数据库失败，历史流量不可用: %v", err)
+	} else {
+		store.SetLogger(s.logger)
+		store.Start()
+		s.traffic = store
+	}
+	s.setupMetrics(cfg)
+	go s.handleSignals()
 
+	// 将 sing-box 生命周期事件推送给前端
+	s.sbManager.OnEvent(func(event singbox.Event) {
+		s.metrics.HandleEvent(event)
+		s.hub.broadcast(map[string]interface{}{
+			"type":  "lifecycle",
+			"event": event,
+		})
+		
+		// 系统代理只在 sing-box 运行期间生效
+		switch event.Type {
+		case singbox.EventStarted:
+			if s.currentConfig().SystemProxy.Enabled {
+				s.enableSystemProxy()
+			}
+		case singbox.EventStopped, singbox.EventExited, singbox.EventCrashLoop:
+			if err := s.sysProxy.Disable(); err != nil {
+				s.logger.Warnf("恢复系统代理设置失败: %v", err)
+			}
+		}
+	})
+	
+	// 推送连接变化
+	s.sbManager.OnConnections(func(update singbox.ConnectionsUpdate) {
+		// 每次轮询都按快照时间采样速率，没有连接变化时也要采样
+		s.series.Sample(traffic.Counter{Upload: update.UploadTotal, Download: update.DownloadTotal}, update.Time)
+		if update.Empty() {
+			return
+		}
+		s.metrics.Record(update)
+		s.breakdown.Record(update)
+		if l := s.audit.Load(); l != nil {
+			l.Record(update)
+		}
+		if s.traffic != nil {
+			s.traffic.Record(update)
+		}
+		if r := s.reporter.Load(); r != nil {
+			r.Record(update)
+		}
+		s.hub.broadcast(map[string]interface{}{
+			"type": "connections",
+			"data": update,
+		})
+	})
+
+	// 按将要使用的内核版本生成配置，启动时再按实际探测到的版本重新生成
+	if version, err := s.sbManager.DetectVersion(); err == nil {
+		s.subManager.SetCoreVersion(version)
+	}
+	s.sbManager.SetRenderer(s.subManager.RenderFor)
+	
 	// 初始化订阅管理器
 	if err := s.subManager.Initialize(cfg); err != nil {
 		s.logger.Warnf("初始化订阅管理器失败: %v", err)
 	}
 
+	s.setupReporter(cfg)
+	s.setupAudit(cfg)
+	
+	// 每次获取到用户信息后检查流量和到期提醒
+	s.alerts = alert.NewWatcher(cfg.Alerts)
+	s.alerts.SetLogger(s.logger)
+	s.alerts.OnAlert(s.handleAlert)
+	s.subManager.OnUserInfo(func(info *xboard.UserInfo) {
+		s.metrics.SetUserInfo(info)
+		s.alerts.Check(info)
+	})
+	
+	s.setupPolicies(cfg)
+	
 	// 注册订阅更新钩子
 	s.subManager.OnUpdate(func(config map[string]interface{}) {
 		s.logger.Info("收到订阅更新，重新加载配置")
@@ -79,6 +197,12 @@ func (s *Server) Start() error {
 		}
 	})
 
+	// 记录节点健康状况，评分过低的节点不参与自动测速选择
+	s.health = health.Open("")
+	s.health.SetLogger(s.logger)
+	s.setupHealth(cfg)
+	s.setupSmart(cfg)
+
 	// 尝试加载缓存的配置
 	if cachedConfig, err := s.subManager.LoadCachedConfig(); err == nil {
 		s.logger.Info("加载缓存配置")
@@ -119,16 +243,70 @@ func (s *Server) setupRoutes() {
 		// 节点管理
 		api.GET("/nodes", s.handleGetNodes)
 		api.POST("/node/select", s.handleSelectNode)
+		api.GET("/smart", s.handleGetSmart)
+		api.POST("/smart/run", s.handleRunSmart)
 		
 		// Sing-box 控制
 		api.POST("/singbox/start", s.handleStartSingbox)
 		api.POST("/singbox/stop", s.handleStopSingbox)
 		api.POST("/singbox/restart", s.handleRestartSingbox)
+		api.POST("/singbox/reload", s.handleReloadSingbox)
+		
+		// 规则模式
+		api.GET("/mode", s.handleGetMode)
+		api.POST("/mode", s.handleSetMode)
+		
+		// TUN 开关
+		api.POST("/tun", s.handleSetTUN)
+		
+		// 入站和局域网共享
+		api.GET("/inbounds", s.handleGetInbounds)
+		api.PUT("/inbounds", s.handleUpdateInbounds)
+		api.POST("/lan", s.handleSetLAN)
+		
+		// 系统代理
+		api.GET("/sysproxy", s.handleGetSystemProxy)
+		api.POST("/sysproxy", s.handleSetSystemProxy)
+		
+		// 定时策略
+		api.GET("/policies", s.handleGetPolicies)
+		api.GET("/policies/events", s.handleGetPolicyEvents)
+		api.POST("/policies/:name/run", s.handleRunPolicy)
+		
+		// 自定义路由规则
+		api.GET("/rules", s.handleGetRules)
+		api.POST("/rules", s.handleAddRule)
+		api.PUT("/rules/:index", s.handleUpdateRule)
+		api.DELETE("/rules/:index", s.handleDeleteRule)
+		api.POST("/rules/:index/move", s.handleMoveRule)
+		
+		// 规则集
+		api.GET("/rulesets", s.handleGetRuleSets)
+		api.POST("/rulesets/refresh", s.handleRefreshRuleSets)
+		
+		// 连接管理
+		api.GET("/connections", s.handleGetConnections)
+		api.DELETE("/connections", s.handleCloseAllConnections)
+		api.DELETE("/connections/:id", s.handleCloseConnection)
+		api.GET("/processes", s.handleGetProcesses)
+		
+		// 流量统计
+		api.GET("/traffic/history", s.handleGetTrafficHistory)
+		api.GET("/traffic/reports", s.handleGetPendingReports)
+		api.GET("/traffic/top", s.handleGetTrafficTop)
+		api.GET("/traffic/series", s.handleGetTrafficSeries)
+		
+		// 日志
+		api.GET("/logs", s.handleGetLogs)
+		api.GET("/logs/stream", s.handleLogStream)
 		
 		// WebSocket
 		api.GET("/ws", s.handleWebSocket)
 	}
 	
+	// Prometheus 指标
+	s.engine.GET("/metrics", s.handleMetrics)
+	
 	// 主页
 	s.engine.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusMovedPermanently, "/static/index.html")
@@ -138,16 +316,27 @@ func (s *Server) setupRoutes() {
 // handleGetStatus 获取状态
 func (s *Server) handleGetStatus(c *gin.Context) {
 	upload, download, uptime := s.sbManager.GetStats()
+	cfg := s.currentConfig()
 	
 	status := gin.H{
-		"running": s.sbManager.IsRunning(),
-		"uptime":  uptime.Seconds(),
+		"running":  s.sbManager.IsRunning(),
+		"state":    s.sbManager.State(),
+		"mode":     s.sbManager.Mode(),
+		"tun":      cfg.TUNEnabled(),
+		"lan":      cfg.LANEnabled(),
+		"sysproxy": cfg.SystemProxy.Enabled,
+		"uptime":   uptime.Seconds(),
 		"stats": gin.H{
 			"upload":   upload,
 			"download": download,
 		},
 	}
 	
+	// 最近一次意外退出
+	if lastExit := s.sbManager.LastExit(); lastExit != nil {
+		status["lastExit"] = lastExit
+	}
+	
 	// 获取用户信息
 	if userInfo, err := s.subManager.GetUserInfo(); err == nil {
 		status["user"] = userInfo
@@ -164,11 +353,13 @@ func (s *Server) handleGetStatus(c *gin.Context) {
 	})
 }
 
-// handleGetConfig 获取配置
+// handleGetConfig 获取配置，入站认证密码以 config.RedactedPassword 代替
 func (s *Server) handleGetConfig(c *gin.Context) {
+	cfg := s.currentConfig()
+	cfg.Singbox.Inbounds = config.RedactPasswords(cfg.Singbox.Inbounds)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    s.config,
+		"data":    cfg,
 	})
 }
 
@@ -183,6 +374,33 @@ func (s *Server) handleUpdateConfig(c *gin.Context) {
 		return
 	}
 	
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	
+	if err := config.RestorePasswords(newConfig.Singbox.Inbounds, s.config.Singbox.Inbounds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	// 先校验再保存，避免写入无法生成 sing-box 配置的应用配置
+	if err := s.subManager.Check(&newConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err := policy.Validate(newConfig.Policies); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
 	// 保存配置
 	if err := config.Save(&newConfig, config.GetDefaultConfigPath()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -193,11 +411,26 @@ func (s *Server) handleUpdateConfig(c *gin.Context) {
 	}
 	
 	s.config = &newConfig
+	s.sbManager.SetConfig(&newConfig)
+	s.sysProxy.SetConfig(newConfig.SystemProxy)
 	
 	// 重新初始化订阅管理器
 	if err := s.subManager.Initialize(&newConfig); err != nil {
 		s.logger.Warnf("重新初始化订阅管理器失败: %v", err)
 	}
+	s.setupReporter(&newConfig)
+	s.setupAudit(&newConfig)
+	s.alerts.SetConfig(newConfig.Alerts)
+	s.setupHealth(&newConfig)
+	s.setupSmart(&newConfig)
+	if err := s.policies.Load(newConfig.Policies); err != nil {
+		s.logger.Warnf("加载定时策略失败: %v", err)
+	}
+	
+	// 按新配置重新生成 sing-box 配置
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -207,7 +440,7 @@ func (s *Server) handleUpdateConfig(c *gin.Context) {
 // handleGetSubscription 获取订阅信息
 func (s *Server) handleGetSubscription(c *gin.Context) {
 	data := gin.H{
-		"url":        s.config.Subscription.URL,
+		"url":        s.currentConfig().Subscription.URL,
 		"lastUpdate": s.subManager.GetLastUpdate(),
 	}
 	
@@ -246,8 +479,17 @@ func (s *Server) handleUpdateSubscription(c *gin.Context) {
 	}
 	
 	// 保存配置
-	s.config.Subscription.URL = req.URL
-	config.Save(s.config, config.GetDefaultConfigPath())
+	s.configMu.Lock()
+	next := *s.config
+	next.Subscription.URL = req.URL
+	if _, token, err := xboard.ParseSubscriptionURL(req.URL); err == nil {
+		next.Subscription.Token = token
+	}
+	err := s.saveConfig(&next)
+	s.configMu.Unlock()
+	if err != nil {
+		s.logger.Warnf("保存配置失败: %v", err)
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -282,7 +524,7 @@ func (s *Server) handleGetNodes(c *gin.Context) {
 	
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    nodes,
+		"data":    s.nodeItems(nodes),
 	})
 }
 
@@ -352,6 +594,387 @@ func (s *Server) handleRestartSingbox(c *gin.Context) {
 	})
 }
 
+// handleReloadSingbox 热重载 sing-box 配置
+func (s *Server) handleReloadSingbox(c *gin.Context) {
+	if err := s.sbManager.Reload(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// handleGetMode 获取规则模式
+func (s *Server) handleGetMode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"mode": s.sbManager.Mode(),
+		},
+	})
+}
+
+// handleSetMode 切换规则模式
+func (s *Server) handleSetMode(c *gin.Context) {
+	var req struct {
+		Mode string `json:"mode" binding:"required"`
+	}
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	if err := config.ValidateMode(req.Mode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	if err := s.setMode(req.Mode); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// handleSetTUN 启用或禁用 TUN 入站
+func (s *Server) handleSetTUN(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	
+	// 先用副本校验 TUN 选项
+	candidate := *s.config
+	candidate.Singbox.Inbounds = append([]config.InboundConfig{}, s.config.Singbox.Inbounds...)
+	candidate.SetTUNEnabled(*req.Enabled)
+	if err := s.subManager.Check(&candidate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	if err := s.saveConfig(&candidate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	// 入站变化会触发完整重启
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// handleGetSystemProxy 获取系统代理状态
+func (s *Server) handleGetSystemProxy(c *gin.Context) {
+	cfg := s.currentConfig()
+	data := gin.H{
+		"enabled": cfg.SystemProxy.Enabled,
+		"active":  s.sysProxy.Active(),
+	}
+	if settings, err := sysproxy.FromConfig(&cfg); err == nil {
+		data["settings"] = settings
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// handleSetSystemProxy 启用或禁用系统代理，sing-box 运行中时立即生效
+func (s *Server) handleSetSystemProxy(c *gin.Context) {
+	var req struct {
+		Enabled *bool    `json:"enabled" binding:"required"`
+		Bypass  []string `json:"bypass"`
+	}
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	
+	proxy := s.config.SystemProxy
+	proxy.Enabled = *req.Enabled
+	if req.Bypass != nil {
+		proxy.Bypass = req.Bypass
+	}
+	s.sysProxy.SetConfig(proxy)
+	
+	// 先应用再保存，设置失败时保留原配置
+	candidate := *s.config
+	candidate.SystemProxy = proxy
+	var err error
+	if !proxy.Enabled {
+		err = s.sysProxy.Disable()
+	} else if s.sbManager.IsRunning() {
+		var settings sysproxy.Settings
+		if settings, err = sysproxy.FromConfig(&candidate); err == nil {
+			err = s.sysProxy.Enable(settings)
+		}
+	}
+	if err != nil {
+		s.sysProxy.SetConfig(s.config.SystemProxy)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	if err := s.saveConfig(&candidate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"active": s.sysProxy.Active(),
+		},
+	})
+}
+
+// currentConfig 在 configMu 保护下复制当前应用配置，供钩子和只读接口使用
+func (s *Server) currentConfig() config.Config {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	return *s.config
+}
+
+// saveConfig 保存修改后的配置副本并替换共享配置，调用方需持有 configMu。
+// 替换出去的配置不再修改，订阅管理器和 sing-box 管理器读取时无需加锁；
+// 保存失败时保持原配置
+func (s *Server) saveConfig(next *config.Config) error {
+	if err := config.Save(next, config.GetDefaultConfigPath()); err != nil {
+		return err
+	}
+	
+	s.config = next
+	s.sbManager.SetConfig(next)
+	s.subManager.SetConfig(next)
+	return nil
+}
+
+// setMode 切换规则模式并保存到应用配置，保存失败时切回原模式
+func (s *Server) setMode(mode string) error {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	
+	previous := s.config
+	next := *previous
+	next.Rules.Mode = mode
+	if err := s.sbManager.SetMode(&next); err != nil {
+		return err
+	}
+	if err := s.saveConfig(&next); err != nil {
+		if rollbackErr := s.sbManager.SetMode(previous); rollbackErr != nil {
+			s.logger.Warnf("恢复规则模式失败: %v", rollbackErr)
+		}
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	
+	s.hub.broadcast(map[string]interface{}{
+		"type": "mode",
+		"mode": mode,
+	})
+	return nil
+}
+
+// enableSystemProxy 将系统代理指向当前的本地入站
+func (s *Server) enableSystemProxy() {
+	cfg := s.currentConfig()
+	settings, err := sysproxy.FromConfig(&cfg)
+	if err == nil {
+		err = s.sysProxy.Enable(settings)
+	}
+	if err != nil {
+		s.logger.Warnf("设置系统代理失败: %v", err)
+	}
+}
+
+// setupReporter 按订阅配置启动或停止流量上报，旧的上报器停止时会把未发送的流量写入队列
+func (s *Server) setupReporter(cfg *config.Config) {
+	if previous := s.reporter.Swap(nil); previous != nil {
+		previous.Stop()
+	}
+	if !cfg.Subscription.ReportTraffic {
+		return
+	}
+	
+	next := reporter.New(s.subManager, cfg.Subscription)
+	next.SetLogger(s.logger)
+	next.Start()
+	s.reporter.Store(next)
+}
+
+// setupAudit 按配置打开或关闭连接审计日志
+func (s *Server) setupAudit(cfg *config.Config) {
+	if previous := s.audit.Swap(nil); previous != nil {
+		previous.Close()
+	}
+	if !cfg.Audit.Enabled {
+		return
+	}
+	
+	next, err := audit.Open(cfg.Audit)
+	if err != nil {
+		s.logger.Warnf("打开审计日志失败: %v", err)
+		return
+	}
+	next.SetLogger(s.logger)
+	s.audit.Store(next)
+}
+
+// handleAlert 推送提醒给前端，按配置发送桌面通知
+func (s *Server) handleAlert(a alert.Alert) {
+	s.hub.broadcast(map[string]interface{}{
+		"type":  "alert",
+		"alert": a,
+	})
+	
+	if s.currentConfig().Alerts.DesktopNotify {
+		if err := alert.DesktopNotify(a); err != nil {
+			s.logger.Warnf("%v", err)
+		}
+	}
+}
+
+// handleSignals 收到退出信号时停止 sing-box、恢复系统代理并写入剩余流量数据
+func (s *Server) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	
+	s.logger.Info("正在退出")
+	if s.sbManager.IsRunning() {
+		s.sbManager.Stop()
+	}
+	if err := s.sysProxy.Disable(); err != nil {
+		s.logger.Warnf("恢复系统代理设置失败: %v", err)
+	}
+	if r := s.reporter.Load(); r != nil {
+		r.Stop()
+	}
+	if s.traffic != nil {
+		s.traffic.Close()
+	}
+	if l := s.audit.Load(); l != nil {
+		l.Close()
+	}
+	if m := s.healthMon.Load(); m != nil {
+		m.Stop()
+	}
+	if smart := s.smart.Load(); smart != nil {
+		smart.Stop()
+	}
+	os.Exit(0)
+}
+
+// handleGetProcesses 获取最近发起过连接的进程，供编写进程规则时选择
+func (s *Server) handleGetProcesses(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.sbManager.RecentProcesses(),
+	})
+}
+
+// handleGetRuleSets 获取规则集及其缓存状态
+func (s *Server) handleGetRuleSets(c *gin.Context) {
+	infos, err := s.subManager.RuleSets().List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    infos,
+	})
+}
+
+// handleRefreshRuleSets 重新下载规则集，请求体可指定标签，为空时刷新全部
+func (s *Server) handleRefreshRuleSets(c *gin.Context) {
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+	
+	if err := s.subManager.RuleSets().Refresh(req.Tags...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	// 改为引用新下载的本地文件
+	if err := s.subManager.Rerender(); err != nil {
+		s.logger.Warnf("重新生成 sing-box 配置失败: %v", err)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
 // handleWebSocket 处理 WebSocket 连接
 func (s *Server) handleWebSocket(c *gin.Context) {
 	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -361,28 +984,179 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	}
 	defer conn.Close()
 	
-	// 定期发送状态更新
+	// 注册到广播中心以接收事件
+	client := s.hub.register()
+	defer s.hub.unregister(client)
+	
+	// 定期发送状态更新和流量排行
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
+	topTicker := time.NewTicker(topInterval)
+	defer topTicker.Stop()
 	
 	for {
 		select {
+		case <-topTicker.C:
+			top := map[string]interface{}{
+				"type": "top",
+				"data": s.breakdown.Top(traffic.DefaultBreakdownWindow, traffic.DefaultTopLimit),
+			}
+			if err := conn.WriteJSON(top); err != nil {
+				s.logger.Debugf("WebSocket 写入失败: %v", err)
+				return
+			}
 		case <-ticker.C:
 			upload, download, uptime := s.sbManager.GetStats()
+			rate := s.series.Current()
 			status := map[string]interface{}{
 				"type":    "status",
 				"running": s.sbManager.IsRunning(),
+				"state":   s.sbManager.State(),
 				"uptime":  uptime.Seconds(),
 				"stats": map[string]interface{}{
 					"upload":   upload,
 					"download": download,
 				},
+				// 最近一秒的速率（字节/秒）
+				"rate": map[string]interface{}{
+					"upload":   rate.Upload,
+					"download": rate.Download,
+				},
 			}
 			
 			if err := conn.WriteJSON(status); err != nil {
 				s.logger.Debugf("WebSocket 写入失败: %v", err)
 				return
 			}
+		case message := <-client.send:
+			if err := conn.WriteJSON(message); err != nil {
+				s.logger.Debugf("WebSocket 写入失败: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// handleGetConnections 获取活动连接
+func (s *Server) handleGetConnections(c *gin.Context) {
+	conns, err := s.sbManager.Connections()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	// 按开始时间倒序
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Start.After(conns[j].Start)
+	})
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    conns,
+	})
+}
+
+// handleCloseConnection 关闭指定连接
+func (s *Server) handleCloseConnection(c *gin.Context) {
+	if err := s.sbManager.CloseConnection(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// handleCloseAllConnections 关闭所有连接
+func (s *Server) handleCloseAllConnections(c *gin.Context) {
+	if err := s.sbManager.CloseAllConnections(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// logFilterFromQuery 从查询参数构建日志过滤条件
+func logFilterFromQuery(c *gin.Context, defaultLimit int) singbox.LogFilter {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 0 {
+		limit = defaultLimit
+	}
+	
+	return singbox.LogFilter{
+		Level:   c.Query("level"),
+		Keyword: c.Query("q"),
+		Limit:   limit,
+	}
+}
+
+// handleGetLogs 获取 sing-box 日志
+func (s *Server) handleGetLogs(c *gin.Context) {
+	filter := logFilterFromQuery(c, 200)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.sbManager.Logs().Query(filter),
+	})
+}
+
+// handleLogStream 通过 WebSocket 推送 sing-box 日志
+func (s *Server) handleLogStream(c *gin.Context) {
+	filter := logFilterFromQuery(c, 100)
+	
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Errorf("WebSocket 升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	
+	// 先订阅再发送历史，避免遗漏
+	entries, cancel := s.sbManager.Logs().Subscribe()
+	defer cancel()
+	
+	for _, entry := range s.sbManager.Logs().Query(filter) {
+		if err := conn.WriteJSON(entry); err != nil {
+			return
+		}
+	}
+	
+	// 客户端断开时结束推送
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	
+	for {
+		select {
+		case entry := <-entries:
+			if !filter.Match(entry) {
+				continue
+			}
+			if err := conn.WriteJSON(entry); err != nil {
+				s.logger.Debugf("WebSocket 写入失败: %v", err)
+				return
+			}
+		case <-closed:
+			return
 		}
 	}
 }
@@ -24,6 +24,27 @@ createApp({
                 upload: 0,
                 download: 0
             },
+            rate: {
+                upload: 0,
+                download: 0
+            },
+            
+            // 活动连接
+            connections: [],
+            
+            // 最近一段时间的流量排行
+            top: null,
+            
+            // 规则模式
+            mode: 'rule',
+            tun: false,
+            sysproxy: false,
+            lan: false,
+            modes: [
+                { value: 'rule', label: '规则' },
+                { value: 'global', label: '全局' },
+                { value: 'direct', label: '直连' }
+            ],
             
             // 消息提示
             message: '',
@@ -52,6 +73,7 @@ createApp({
             await this.getStatus();
             await this.getSubscription();
             await this.getConfig();
+            await this.getConnections();
         },
         
         // 获取状态
@@ -66,6 +88,10 @@ createApp({
                     this.stats = data.data.stats || { upload: 0, download: 0 };
                     this.userInfo = data.data.user || null;
                     this.lastUpdate = data.data.lastUpdate || null;
+                    this.mode = data.data.mode || 'rule';
+                    this.tun = !!data.data.tun;
+                    this.sysproxy = !!data.data.sysproxy;
+                    this.lan = !!data.data.lan;
                 }
             } catch (error) {
                 console.error('获取状态失败:', error);
@@ -185,6 +211,169 @@ createApp({
             }
         },
         
+        // 切换规则模式
+        async setMode(mode) {
+            if (mode === this.mode) {
+                return;
+            }
+            
+            try {
+                const response = await fetch('/api/mode', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ mode })
+                });
+                
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.mode = mode;
+                    this.showMessage('规则模式已切换', 'success');
+                } else {
+                    this.showMessage(data.error || '切换规则模式失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('切换规则模式失败: ' + error.message, 'error');
+            }
+        },
+        
+        // 启用或禁用 TUN
+        async setTUN(enabled) {
+            try {
+                const response = await fetch('/api/tun', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ enabled })
+                });
+                
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.tun = enabled;
+                    this.showMessage(enabled ? 'TUN 已启用' : 'TUN 已禁用', 'success');
+                } else {
+                    this.showMessage(data.error || '切换 TUN 失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('切换 TUN 失败: ' + error.message, 'error');
+            }
+        },
+        
+        // 允许或禁止局域网连接
+        async setLAN(enabled) {
+            try {
+                const response = await fetch('/api/lan', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ enabled })
+                });
+                
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.lan = enabled;
+                    this.showMessage(enabled ? '已允许局域网连接' : '已禁止局域网连接', 'success');
+                } else {
+                    this.showMessage(data.error || '切换局域网共享失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('切换局域网共享失败: ' + error.message, 'error');
+            }
+        },
+        
+        // 启用或禁用系统代理
+        async setSystemProxy(enabled) {
+            try {
+                const response = await fetch('/api/sysproxy', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json'
+                    },
+                    body: JSON.stringify({ enabled })
+                });
+                
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.sysproxy = enabled;
+                    this.showMessage(enabled ? '系统代理已启用' : '系统代理已禁用', 'success');
+                } else {
+                    this.showMessage(data.error || '切换系统代理失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('切换系统代理失败: ' + error.message, 'error');
+            }
+        },
+        
+        // 获取活动连接
+        async getConnections() {
+            try {
+                const response = await fetch('/api/connections');
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.connections = data.data || [];
+                }
+            } catch (error) {
+                console.error('获取连接失败:', error);
+            }
+        },
+        
+        // 应用连接变化
+        applyConnectionsUpdate(update) {
+            const closed = new Set((update.closed || []).map(conn => conn.id));
+            const changed = {};
+            (update.updated || []).forEach(conn => {
+                changed[conn.id] = conn;
+            });
+            
+            this.connections = (update.added || []).concat(
+                this.connections
+                    .filter(conn => !closed.has(conn.id))
+                    .map(conn => changed[conn.id] || conn)
+            );
+        },
+        
+        // 关闭连接
+        async closeConnection(id) {
+            try {
+                const response = await fetch(`/api/connections/${encodeURIComponent(id)}`, {
+                    method: 'DELETE'
+                });
+                const data = await response.json();
+                
+                if (!data.success) {
+                    this.showMessage(data.error || '关闭连接失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('关闭连接失败: ' + error.message, 'error');
+            }
+        },
+        
+        // 关闭所有连接
+        async closeAllConnections() {
+            try {
+                const response = await fetch('/api/connections', {
+                    method: 'DELETE'
+                });
+                const data = await response.json();
+                
+                if (data.success) {
+                    this.showMessage('已关闭所有连接', 'success');
+                } else {
+                    this.showMessage(data.error || '关闭连接失败', 'error');
+                }
+            } catch (error) {
+                this.showMessage('关闭连接失败: ' + error.message, 'error');
+            }
+        },
+        
         // 切换 sing-box 状态
         async toggleSingbox() {
             if (this.isRunning) {
@@ -281,6 +470,26 @@ createApp({
                         this.isRunning = data.running;
                         this.uptime = data.uptime || 0;
                         this.stats = data.stats || { upload: 0, download: 0 };
+                        this.rate = data.rate || { upload: 0, download: 0 };
+                    } else if (data.type === 'lifecycle') {
+                        this.handleLifecycleEvent(data.event);
+                    } else if (data.type === 'connections') {
+                        this.applyConnectionsUpdate(data.data);
+                    } else if (data.type === 'mode') {
+                        this.mode = data.mode;
+                    } else if (data.type === 'top') {
+                        this.top = data.data;
+                    } else if (data.type === 'policy') {
+                        const event = data.event;
+                        if (event.error) {
+                            this.showMessage(`策略 ${event.policy} 执行失败: ${event.error}`, 'error');
+                        } else {
+                            this.showMessage(`已执行策略 ${event.policy}: ${event.actions.join('，')}`, 'info');
+                        }
+                    } else if (data.type === 'smart') {
+                        this.showMessage(`已自动切换到节点 ${data.decision.to}（${data.decision.reason}）`, 'info');
+                    } else if (data.type === 'alert') {
+                        this.showMessage(`${data.alert.title}：${data.alert.message}`, data.alert.level === 'critical' ? 'error' : 'info');
                     }
                 } catch (error) {
                     console.error('WebSocket 消息解析失败:', error);
@@ -300,6 +509,27 @@ createApp({
             };
         },
         
+        // 处理 sing-box 生命周期事件
+        handleLifecycleEvent(event) {
+            switch (event.type) {
+                case 'exited':
+                    this.showMessage(event.message, 'error');
+                    break;
+                case 'restarting':
+                    this.showMessage(`sing-box 已退出，${event.message}`, 'info');
+                    break;
+                case 'crash_loop':
+                    this.showMessage(event.message, 'error');
+                    break;
+                case 'started':
+                    this.isRunning = true;
+                    break;
+                case 'reloaded':
+                    this.showMessage('配置已热重载', 'success');
+                    break;
+            }
+        },
+        
         // 显示消息
         showMessage(message, type = 'info') {
             this.message = message;
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/singbox-xboard-client/internal/reporter"
+	"github.com/your-username/singbox-xboard-client/internal/traffic"
+)
+
+// topInterval 通过 WebSocket 推送流量排行的间隔
+const topInterval = 5 * time.Second
+
+// handleGetTrafficHistory 查询历史流量，range 为时间范围（如 24h、7d），group_by 可选 node、hour、day、month
+func (s *Server) handleGetTrafficHistory(c *gin.Context) {
+	if s.traffic == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "流量数据库不可用",
+		})
+		return
+	}
+
+	duration, err := traffic.ParseRange(c.Query("range"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	since := time.Now().Add(-duration)
+
+	groupBy := c.DefaultQuery("group_by", traffic.GroupByNode)
+	var items interface{}
+	switch groupBy {
+	case traffic.GroupByNode:
+		items, err = s.traffic.ByNode(since)
+	case traffic.GroupByHour, traffic.GroupByDay, traffic.GroupByMonth:
+		items, err = s.traffic.ByPeriod(groupBy, since)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "group_by 可选 node、hour、day、month",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"since":    since,
+			"group_by": groupBy,
+			"items":    items,
+		},
+	})
+}
+
+// handleGetPendingReports 获取尚未送达面板的流量上报
+func (s *Server) handleGetPendingReports(c *gin.Context) {
+	reports := []reporter.Report{}
+	if r := s.reporter.Load(); r != nil {
+		reports = r.Pending()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reports,
+	})
+}
+
+// handleGetTrafficTop 获取最近一段时间内按规则、出站和目标域名的流量排行，
+// window 为时间窗口（如 5m，最长 1h），limit 为每张排行的条数
+func (s *Server) handleGetTrafficTop(c *gin.Context) {
+	window := traffic.DefaultBreakdownWindow
+	if value := c.Query("window"); value != "" {
+		duration, err := traffic.ParseRange(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		window = duration
+	}
+
+	limit := traffic.DefaultTopLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "limit 无效",
+			})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.breakdown.Top(window, limit),
+	})
+}
+
+// handleGetTrafficSeries 获取速率序列，resolution 可选 1s（最近 5 分钟）和 1m（最近 24 小时）
+func (s *Server) handleGetTrafficSeries(c *gin.Context) {
+	resolution := c.DefaultQuery("resolution", traffic.PerSecond)
+	points, err := s.series.Query(resolution)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"resolution": resolution,
+			"current":    s.series.Current(),
+			"points":     points,
+		},
+	})
+}
@@ -5,28 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
-	"time"
+	"sync/atomic"
 
+	"github.com/your-username/singbox-xboard-client/internal/alert"
 	"github.com/your-username/singbox-xboard-client/internal/config"
+	"github.com/your-username/singbox-xboard-client/internal/reporter"
 	"github.com/your-username/singbox-xboard-client/internal/singbox"
 	"github.com/your-username/singbox-xboard-client/internal/subscription"
+	"github.com/your-username/singbox-xboard-client/pkg/xboard"
 )
 
+// AlertCallback 接收流量和到期提醒，由 Android 端实现并显示通知。
+// kind 为 traffic 或 expiry，level 为 warning 或 critical
+type AlertCallback interface {
+	OnAlert(kind, level string, threshold int, title, message string)
+}
+
 // Client 是给 Android 使用的客户端接口
 type Client struct {
 	config     *config.Config
 	singbox    *singbox.Manager
 	subManager *subscription.Manager
+	reporter   atomic.Pointer[reporter.Reporter] // 未启用流量上报时为空
+	alerts     *alert.Watcher
+	callback   AlertCallback
 	mu         sync.RWMutex
 	isRunning  bool
 }
 
 // NewClient 创建新的客户端实例
 func NewClient() *Client {
-	return &Client{
+	c := &Client{
 		config:     config.DefaultConfig(),
 		subManager: subscription.NewManager(),
 	}
+
+	// 每次获取到用户信息后检查提醒，钩子在独立的 goroutine 中执行
+	c.alerts = alert.NewWatcher(c.config.Alerts)
+	c.alerts.OnAlert(func(a alert.Alert) {
+		c.mu.RLock()
+		callback := c.callback
+		c.mu.RUnlock()
+
+		if callback != nil {
+			callback.OnAlert(a.Kind, a.Level, a.Threshold, a.Title, a.Message)
+		}
+	})
+	c.subManager.OnUserInfo(func(info *xboard.UserInfo) {
+		c.alerts.Check(info)
+	})
+	return c
+}
+
+// SetAlertCallback 设置提醒回调，传入 nil 取消
+func (c *Client) SetAlertCallback(callback AlertCallback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callback = callback
 }
 
 // Initialize 初始化客户端
@@ -43,22 +78,55 @@ func (c *Client) Initialize(configPath string) error {
 		c.config = cfg
 	}
 
-	// 初始化 singbox 管理器
-	c.singbox = singbox.NewManager(c.config)
+	// 初始化 singbox 管理器，重复初始化时沿用已有实例，避免重复注册回调
+	if c.singbox == nil {
+		c.singbox = singbox.NewManager(c.config)
+
+		// 设置订阅更新回调
+		c.subManager.OnUpdate(func(singboxConfig map[string]interface{}) {
+			c.singbox.UpdateConfig(singboxConfig)
+		})
+		c.singbox.SetRenderer(c.subManager.RenderFor)
+
+		// 停止时连接钩子在持有 c.mu 的情况下触发，这里不能再加锁
+		c.singbox.OnConnections(func(update singbox.ConnectionsUpdate) {
+			if r := c.reporter.Load(); r != nil {
+				r.Record(update)
+			}
+		})
+	} else {
+		c.singbox.SetConfig(c.config)
+	}
+
+	// 按将要使用的内核版本生成配置
+	if version, err := c.singbox.DetectVersion(); err == nil {
+		c.subManager.SetCoreVersion(version)
+	}
 
 	// 初始化订阅管理器
 	if err := c.subManager.Initialize(c.config); err != nil {
 		return fmt.Errorf("初始化订阅失败: %v", err)
 	}
 
-	// 设置订阅更新回调
-	c.subManager.OnUpdate(func(singboxConfig map[string]interface{}) {
-		c.singbox.UpdateConfig(singboxConfig)
-	})
-
+	c.alerts.SetConfig(c.config.Alerts)
+	c.setupReporter()
 	return nil
 }
 
+// setupReporter 按订阅配置启动或停止流量上报
+func (c *Client) setupReporter() {
+	if previous := c.reporter.Swap(nil); previous != nil {
+		previous.Stop()
+	}
+	if !c.config.Subscription.ReportTraffic {
+		return
+	}
+
+	next := reporter.New(c.subManager, c.config.Subscription)
+	next.Start()
+	c.reporter.Store(next)
+}
+
 // Start 启动 VPN 服务
 func (c *Client) Start() error {
 	c.mu.Lock()
@@ -95,7 +163,20 @@ func (c *Client) Stop() error {
 
 // UpdateSubscription 更新订阅
 func (c *Client) UpdateSubscription(url string) error {
-	return c.subManager.UpdateSubscription(url)
+	if err := c.subManager.UpdateSubscription(url); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := *c.config
+	next.Subscription.URL = url
+	if _, token, err := xboard.ParseSubscriptionURL(url); err == nil {
+		next.Subscription.Token = token
+	}
+	c.config = &next
+	c.subManager.SetConfig(&next)
+	return nil
 }
 
 // RefreshSubscription 刷新当前订阅
@@ -118,7 +199,7 @@ func (c *Client) GetStatus() string {
 	}
 
 	// 添加用户信息
-	if userInfo := c.subManager.GetUserInfo(); userInfo != nil {
+	if userInfo, err := c.subManager.GetUserInfo(); err == nil {
 		status["user"] = map[string]interface{}{
 			"email":       userInfo.Email,
 			"upload":      userInfo.Upload,
@@ -134,7 +215,10 @@ func (c *Client) GetStatus() string {
 
 // GetNodes 获取节点列表
 func (c *Client) GetNodes() string {
-	nodes := c.subManager.GetNodes()
+	nodes, err := c.subManager.GetNodeList()
+	if err != nil {
+		return "[]"
+	}
 	data, _ := json.Marshal(nodes)
 	return string(data)
 }
@@ -146,6 +230,112 @@ func (c *Client) SelectNode(nodeTag string) error {
 	return fmt.Errorf("节点选择功能尚未实现")
 }
 
+// GetMode 获取当前规则模式
+func (c *Client) GetMode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.singbox == nil {
+		return c.config.Rules.Mode
+	}
+	return c.singbox.Mode()
+}
+
+// SetMode 切换规则模式：rule, global, direct
+func (c *Client) SetMode(mode string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.singbox == nil {
+		return fmt.Errorf("客户端未初始化")
+	}
+
+	// 修改副本，订阅管理器可能正在按原配置生成 sing-box 配置
+	next := *c.config
+	next.Rules.Mode = mode
+	if err := c.singbox.SetMode(&next); err != nil {
+		return fmt.Errorf("切换规则模式失败: %v", err)
+	}
+
+	if err := c.saveConfig(&next); err != nil {
+		c.singbox.SetMode(c.config)
+		return err
+	}
+	return nil
+}
+
+// IsTUNEnabled 是否启用了 TUN 入站
+func (c *Client) IsTUNEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.TUNEnabled()
+}
+
+// SetTUNEnabled 启用或禁用 TUN 入站，运行中会重启 sing-box
+func (c *Client) SetTUNEnabled(enabled bool) error {
+	c.mu.Lock()
+	next := c.copyConfig()
+	next.SetTUNEnabled(enabled)
+	err := c.saveConfig(next)
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if err := c.subManager.Rerender(); err != nil {
+		return fmt.Errorf("生成配置失败: %v", err)
+	}
+	return nil
+}
+
+// IsLANEnabled 是否允许局域网连接 mixed 入站
+func (c *Client) IsLANEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.LANEnabled()
+}
+
+// SetLANEnabled 允许或禁止局域网连接 mixed 入站，运行中会重启 sing-box。
+// mixed 入站未设置带密码的认证用户时不能允许
+func (c *Client) SetLANEnabled(enabled bool) error {
+	c.mu.Lock()
+	next := c.copyConfig()
+	if err := next.SetLANEnabled(enabled); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	err := c.saveConfig(next)
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if err := c.subManager.Rerender(); err != nil {
+		return fmt.Errorf("生成配置失败: %v", err)
+	}
+	return nil
+}
+
+// copyConfig 复制当前配置和入站列表，修改副本不影响正在生成配置的订阅管理器，调用方需持有 c.mu
+func (c *Client) copyConfig() *config.Config {
+	next := *c.config
+	next.Singbox.Inbounds = append([]config.InboundConfig(nil), c.config.Singbox.Inbounds...)
+	return &next
+}
+
+// saveConfig 保存修改后的配置副本并替换当前配置，保存失败时保持原配置，调用方需持有 c.mu
+func (c *Client) saveConfig(next *config.Config) error {
+	if err := config.Save(next, config.GetDefaultConfigPath()); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+	c.config = next
+	if c.singbox != nil {
+		c.singbox.SetConfig(next)
+	}
+	c.subManager.SetConfig(next)
+	return nil
+}
+
 // GetConfig 获取当前配置
 func (c *Client) GetConfig() string {
 	c.mu.RLock()
@@ -157,29 +347,53 @@ func (c *Client) GetConfig() string {
 
 // UpdateConfig 更新配置
 func (c *Client) UpdateConfig(configJSON string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	var cfg config.Config
 	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
 		return fmt.Errorf("解析配置失败: %v", err)
 	}
 
-	c.config = &cfg
-	
 	// 保存配置
-	if err := config.Save(c.config, config.GetDefaultConfigPath()); err != nil {
+	if err := config.Save(&cfg, config.GetDefaultConfigPath()); err != nil {
 		return fmt.Errorf("保存配置失败: %v", err)
 	}
 
+	c.mu.Lock()
+	c.config = &cfg
+	c.mu.Unlock()
+
 	// 重新初始化
-	return c.Initialize("")
+	if err := c.Initialize(""); err != nil {
+		return err
+	}
+
+	// 按新配置重新生成 sing-box 配置
+	if err := c.subManager.Rerender(); err != nil {
+		return fmt.Errorf("生成配置失败: %v", err)
+	}
+	return nil
 }
 
 // GetLogs 获取日志（最近的N条）
 func (c *Client) GetLogs(limit int) string {
-	// TODO: 实现日志获取
-	return "[]"
+	return c.FilterLogs("", "", limit)
+}
+
+// FilterLogs 按最低级别和关键字过滤日志，返回最近的N条
+func (c *Client) FilterLogs(level, keyword string, limit int) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.singbox == nil {
+		return "[]"
+	}
+
+	entries := c.singbox.Logs().Query(singbox.LogFilter{
+		Level:   level,
+		Keyword: keyword,
+		Limit:   limit,
+	})
+	data, _ := json.Marshal(entries)
+	return string(data)
 }
 
 // TestConnection 测试连接
@@ -1,7 +1,6 @@
 package xboard
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -110,18 +109,13 @@ func (c *Client) GetNodeList() ([]NodeInfo, error) {
 	return nodes, nil
 }
 
-// ReportTraffic 上报流量使用情况
-func (c *Client) ReportTraffic(upload, download int64, nodeID int) error {
-	c.logger.Debugf("上报流量: 上传=%d, 下载=%d, 节点=%d", upload, download, nodeID)
-
-	data := map[string]interface{}{
-		"upload":   upload,
-		"download": download,
-		"node_id":  nodeID,
-	}
+// ReportTraffic 上报流量使用情况，ReportID 同时放在 Idempotency-Key 请求头中供面板去重
+func (c *Client) ReportTraffic(report TrafficReport) error {
+	c.logger.Debugf("上报流量: 上传=%d, 下载=%d, 节点=%d, 编号=%s", report.Upload, report.Download, report.NodeID, report.ReportID)
 
 	resp, err := c.httpClient.R().
-		SetBody(data).
+		SetHeader("Idempotency-Key", report.ReportID).
+		SetBody(report).
 		Post(c.baseURL + "/api/v1/user/traffic")
 
 	if err != nil {
@@ -129,13 +123,15 @@ func (c *Client) ReportTraffic(upload, download int64, nodeID int) error {
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("服务器返回错误: %s", resp.Status())
+		return &StatusError{Code: resp.StatusCode(), Status: resp.Status()}
 	}
 
 	return nil
 }
 
 // GetSingboxConfig 获取 sing-box 格式的配置
+//
+// Deprecated: 生成结果不包含应用配置，请使用 GetSubscription 获取节点后交给 render 包生成
 func (c *Client) GetSingboxConfig() (map[string]interface{}, error) {
 	// 获取订阅信息
 	sub, err := c.GetSubscription()
@@ -86,6 +86,25 @@ type TrafficLog struct {
 	LogTime    time.Time `json:"log_time"`
 }
 
+// TrafficReport 一次流量上报，同一 ReportID 重复上报时面板应只计一次
+type TrafficReport struct {
+	ReportID  string `json:"report_id"`
+	NodeID    int    `json:"node_id"`
+	Upload    int64  `json:"upload"`
+	Download  int64  `json:"download"`
+	Timestamp int64  `json:"timestamp"` // 统计时间（Unix 秒）
+}
+
+// StatusError 面板返回的非 200 状态
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("服务器返回错误: %s", e.Status)
+}
+
 // APIError API 错误
 type APIError struct {
 	Code    int    `json:"code"`